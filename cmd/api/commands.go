@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/mail"
+	"os"
+	"shop/internal/dto"
+	"shop/internal/env"
+	"shop/internal/jwtkeys"
+	"shop/internal/repositories"
+	"shop/internal/services"
+	"shop/migrations"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+func runCommand(name string, args []string) {
+	var err error
+	switch name {
+	case "create-admin":
+		err = createAdmin(args)
+	case "migrate":
+		err = runMigrate(args)
+	case "jwt-key":
+		err = generateJWTKey(args)
+	default:
+		err = fmt.Errorf("unknown command %q, available commands: create-admin, migrate, jwt-key", name)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// createAdmin creates an administrator account. It is the way to get the first
+// administrator, later ones can also be created through the admin API.
+// The password is taken from ADMIN_PASSWORD or read from the standard input.
+func createAdmin(args []string) error {
+	flags := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	email := flags.String("email", "", "administrator email")
+	username := flags.String("username", "", "administrator username")
+	_ = flags.Parse(args)
+
+	if _, err := mail.ParseAddress(*email); err != nil {
+		return errors.New("a valid -email is required")
+	}
+	if *username == "" {
+		return errors.New("-username is required")
+	}
+
+	password := env.GetEnvString("ADMIN_PASSWORD", "")
+	if password == "" {
+		fmt.Fprint(os.Stderr, "Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("failed to read password: %w", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if len(password) < 8 {
+		return errors.New("password must be at least 8 characters long")
+	}
+
+	db := connectDB(slog.Default())
+	// CreateUser neither issues tokens nor sends emails, so those services are not needed here.
+	userService := services.NewUserService(repositories.NewUserRepository(db), repositories.NewCartRepository(db), nil, nil, nil, nil, slog.Default())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	user, err := userService.CreateUser(dto.CreateUserRequest{
+		Email:    *email,
+		Username: *username,
+		Password: password,
+		Type:     dto.TypeAdministrator.String(),
+	}, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create administrator: %w", err)
+	}
+
+	log.Printf("Created administrator %s with id %d", user.Email, user.ID)
+	return nil
+}
+
+// runMigrate manages the database schema with the migrations embedded into the binary:
+//
+//	migrate up           applies all pending migrations
+//	migrate down [N]     rolls back the last N migrations, 1 by default
+//	migrate status       prints the current and the expected schema versions
+//	migrate force V      sets the version to V without running migrations, to recover
+//	                     from a migration that failed halfway
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up|down [N]|status|force V")
+	}
+
+	m, err := migrations.New(databaseDSN())
+	if err != nil {
+		return fmt.Errorf("failed to open migrations: %w", err)
+	}
+	defer m.Close()
+
+	switch args[0] {
+	case "up":
+		err = m.Up()
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return errors.New("number of migrations to roll back must be a positive integer")
+			}
+		}
+		err = m.Steps(-steps)
+	case "force":
+		if len(args) < 2 {
+			return errors.New("usage: migrate force V")
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			return errors.New("version must be an integer")
+		}
+		err = m.Force(version)
+	case "status":
+		version, dirty, versionErr := m.Version()
+		if errors.Is(versionErr, migrate.ErrNilVersion) {
+			log.Printf("No migrations applied, expected version %d", migrations.Version)
+			return nil
+		}
+		if versionErr != nil {
+			return versionErr
+		}
+		log.Printf("Schema version %d (dirty: %t), expected version %d", version, dirty, migrations.Version)
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q, available commands: up, down, status, force", args[0])
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		log.Println("No migrations to apply")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return err
+	}
+	log.Printf("Schema version %d (dirty: %t)", version, dirty)
+	return nil
+}
+
+// generateJWTKey writes a new private key for signing access tokens into the key
+// directory and prints its kid. Rotating keys takes three steps:
+//
+//  1. generate a key with this command and restart with JWT_SIGNING_KEY_ID set to it,
+//     the previous key keeps verifying the tokens it signed
+//  2. once ACCESS_TOKEN_TTL has passed, replace the previous key with its public half
+//     (openssl pkey -in <kid>.pem -pubout -out <kid>.pub.pem) or delete it
+//  3. delete the public half when no other service needs it any more
+//
+// Other services fetch the keys from /.well-known/jwks.json and should refresh them at
+// least as often as keys are rotated.
+func generateJWTKey(args []string) error {
+	flags := flag.NewFlagSet("jwt-key", flag.ExitOnError)
+	dir := flags.String("dir", env.GetEnvString("JWT_KEYS_DIR", ""), "key directory, JWT_KEYS_DIR by default")
+	alg := flags.String("alg", "EdDSA", "signing algorithm, RS256 or EdDSA")
+	_ = flags.Parse(args)
+
+	if *dir == "" {
+		return errors.New("-dir or JWT_KEYS_DIR is required")
+	}
+
+	kid, err := jwtkeys.Generate(*dir, *alg)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	log.Printf("Generated %s key %s, set JWT_SIGNING_KEY_ID=%s to sign tokens with it", *alg, kid, kid)
+	return nil
+}
@@ -1,12 +1,25 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
+	"os/signal"
 	_ "shop/docs"
 	"shop/internal/app"
 	"shop/internal/env"
+	"shop/internal/jwtkeys"
+	"shop/internal/logging"
+	"shop/internal/mailer"
+	"shop/internal/metrics"
+	"shop/internal/ratelimit"
 	"shop/internal/repositories"
+	"shop/internal/search"
+	"shop/internal/tracing"
+	"shop/migrations"
+	"syscall"
 	"time"
 
 	_ "github.com/joho/godotenv/autoload"
@@ -20,23 +33,97 @@ import (
 // @securityDefinitions.apikey ApiKeyAuth
 // @in header
 // @name Authorization
-// @description Type "Bearer" followed by a space and JWT token.
+// @description Type "Bearer" followed by a space and JWT token. Routes that list a scope in their description also accept a seller API key in place of the token.
 func main() {
-	db := connectDB()
+	logger := logging.New(
+		os.Stdout,
+		env.GetEnvString("LOG_FORMAT", "json"),
+		logging.ParseLevel(env.GetEnvString("LOG_LEVEL", "info")),
+	)
+	// Libraries and commands that still use the log package go through it as well.
+	slog.SetDefault(logger)
+
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1], os.Args[2:])
+		return
+	}
+
+	// Checked before anything else so that a misconfigured production instance fails fast.
+	jwtKeys := newJWTKeys(logger)
+
+	shutdownTracing, err := tracing.Setup(
+		env.GetEnvString("OTEL_TRACES_EXPORTER", "none"),
+		env.GetEnvString("OTEL_SERVICE_NAME", "shop"),
+		context.Background(),
+	)
+	if err != nil {
+		fatal(logger, "Failed to set up tracing", slog.Any("error", err))
+	}
+
+	db := connectDB(logger)
+	sqlDB, err := db.DB()
+	if err != nil {
+		fatal(logger, "Failed to get database connection pool", slog.Any("error", err))
+	}
+	appMetrics := metrics.New(sqlDB)
+	if err := db.Use(metrics.NewGormPlugin(appMetrics)); err != nil {
+		fatal(logger, "Failed to register database metrics", slog.Any("error", err))
+	}
+	if err := db.Use(tracing.NewGormPlugin()); err != nil {
+		fatal(logger, "Failed to register database tracing", slog.Any("error", err))
+	}
+
 	userRep := repositories.NewUserRepository(db)
 	productRep := repositories.NewProductRepository(db)
 	cartRep := repositories.NewCartRepository(db)
 	cartItemRep := repositories.NewCartItemRepository(db)
+	orderRep := repositories.NewOrderRepository(db)
+	categoryRep := repositories.NewCategoryRepository(db)
+	sellerApplicationRep := repositories.NewSellerApplicationRepository(db)
+	tokenRep := repositories.NewTokenRepository(db)
+	passwordResetRep := repositories.NewPasswordResetRepository(db)
+	emailVerificationRep := repositories.NewEmailVerificationRepository(db)
+	loginAttemptRep := repositories.NewLoginAttemptRepository(db)
+	accountUnlockRep := repositories.NewAccountUnlockRepository(db)
+	twoFactorRep := repositories.NewTwoFactorRepository(db)
+	apiKeyRep := repositories.NewAPIKeyRepository(db)
+	schemaRep := repositories.NewSchemaRepository(db)
+	checkSchemaVersion(schemaRep, logger)
+
+	searchIndex := newSearchIndex(db, productRep, logger)
+
+	application := app.GetApplication(
+		userRep, productRep, cartRep, cartItemRep, orderRep, categoryRep, sellerApplicationRep, tokenRep, passwordResetRep,
+		emailVerificationRep, loginAttemptRep, accountUnlockRep, twoFactorRep, apiKeyRep, schemaRep, searchIndex, jwtKeys, newMailer(logger),
+		logger, appMetrics, newRateLimitStore(logger),
+	)
 
-	application := app.GetApplication(userRep, productRep, cartRep, cartItemRep)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	serveErr := application.Serve(ctx)
 
-	if err := application.Serve(); err != nil {
-		panic(err)
+	if err := sqlDB.Close(); err != nil {
+		logger.Error("Failed to close database connections", slog.Any("error", err))
 	}
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Error("Failed to flush traces", slog.Any("error", err))
+	}
+	if serveErr != nil {
+		fatal(logger, "Server failed", slog.Any("error", serveErr))
+	}
+	logger.Info("Server stopped")
+}
+
+// fatal logs the message at error level and exits.
+func fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
 }
 
-func connectDB() *gorm.DB {
-	dsn := fmt.Sprintf(
+func databaseDSN() string {
+	return fmt.Sprintf(
 		"%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=true&loc=Local",
 		env.GetEnvString("DB_USER", "mysql"),
 		env.GetEnvString("DB_PASSWORD", "mysql"),
@@ -44,6 +131,14 @@ func connectDB() *gorm.DB {
 		env.GetEnvString("DB_PORT", "3306"),
 		env.GetEnvString("DB_NAME", "mysql"),
 	)
+}
+
+func connectDB(logger *slog.Logger) *gorm.DB {
+	dsn := databaseDSN()
+	config := &gorm.Config{
+		TranslateError: true,
+		Logger:         logging.NewGormLogger(logger, env.GetEnvDuration("SLOW_QUERY_THRESHOLD", 200*time.Millisecond)),
+	}
 
 	var db *gorm.DB
 	var err error
@@ -51,19 +146,159 @@ func connectDB() *gorm.DB {
 	retryDelay := 3 * time.Second
 
 	for i := 0; i < maxRetries; i++ {
-		db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+		db, err = gorm.Open(mysql.Open(dsn), config)
 		if err == nil {
-			log.Println("Успешно подключено к базе данных")
+			logger.Info("Connected to the database")
 			return db
 		}
 
-		log.Printf("Попытка подключения к БД %d/%d не удалась: %v", i+1, maxRetries, err)
+		logger.Warn("Failed to connect to the database",
+			slog.Int("attempt", i+1), slog.Int("max_attempts", maxRetries), slog.Any("error", err))
 		if i < maxRetries-1 {
-			log.Printf("Повторная попытка через %v...", retryDelay)
 			time.Sleep(retryDelay)
 		}
 	}
 
-	log.Printf("Не удалось подключиться к БД после %d попыток. DSN: %s", maxRetries, dsn)
-	panic(fmt.Sprintf("не удалось подключиться к базе данных: %v", err))
+	fatal(logger, "Giving up connecting to the database",
+		slog.String("host", env.GetEnvString("DB_HOST", "localhost")), slog.Any("error", err))
+	return nil
+}
+
+// checkSchemaVersion refuses to start if the database schema is not at the version of
+// the latest embedded migration, since the models would not match the tables.
+func checkSchemaVersion(schemaRep repositories.SchemaRepository, logger *slog.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	version, dirty, err := schemaRep.GetVersion(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		fatal(logger, "Database has no migrations applied, run \"migrate up\" first")
+	}
+	if err != nil {
+		fatal(logger, "Failed to read database schema version", slog.Any("error", err))
+	}
+	if dirty {
+		fatal(logger, "Migration failed halfway, fix the schema and run \"migrate force\"", slog.Uint64("version", uint64(version)))
+	}
+	if version != migrations.Version {
+		fatal(logger, "Database schema is outdated, run \"migrate up\"",
+			slog.Uint64("version", uint64(version)), slog.Uint64("expected_version", uint64(migrations.Version)))
+	}
+}
+
+// defaultJWTSecret is the JWT_SECRET used when none is set, only acceptable in development.
+const defaultJWTSecret = "some_secret"
+
+// minJWTSecretLength is the shortest JWT_SECRET used without a warning, the size of an
+// HS256 key.
+const minJWTSecretLength = 32
+
+// newJWTKeys loads the keys access tokens are signed with. Keys in JWT_KEYS_DIR take
+// precedence over JWT_SECRET, and JWT_SIGNING_KEY_ID picks the signing one among them.
+// With APP_ENV=production the application refuses to start without JWT_KEYS_DIR:
+// tokens signed with the secret can't be verified by other services through the JWKS.
+func newJWTKeys(logger *slog.Logger) *jwtkeys.KeySet {
+	mode := appEnv(logger)
+	if dir := env.GetEnvString("JWT_KEYS_DIR", ""); dir != "" {
+		keys, err := jwtkeys.LoadDir(dir, env.GetEnvString("JWT_SIGNING_KEY_ID", ""))
+		if err != nil {
+			fatal(logger, "Failed to load JWT keys", slog.String("dir", dir), slog.Any("error", err))
+		}
+		logger.Info("Loaded JWT keys", slog.String("dir", dir), slog.String("signing_key_id", keys.SigningKeyID()))
+		return keys
+	}
+
+	if mode == "production" {
+		fatal(logger, "JWT_KEYS_DIR must be set in production, generate a key with the jwt-key command")
+	}
+
+	secret := env.GetEnvString("JWT_SECRET", "")
+	switch {
+	case secret == "":
+		logger.Warn("JWT_SECRET is not set, signing tokens with the insecure default secret")
+		secret = defaultJWTSecret
+	case len(secret) < minJWTSecretLength:
+		logger.Warn("JWT_SECRET is shorter than recommended", slog.Int("min_length", minJWTSecretLength))
+	}
+
+	return jwtkeys.NewHMAC([]byte(secret))
+}
+
+func newSearchIndex(db *gorm.DB, productRep repositories.ProductRepository, logger *slog.Logger) search.SearchIndex {
+	backend := env.GetEnvString("SEARCH_BACKEND", "mysql")
+	switch backend {
+	case "mysql":
+		return search.NewMySQLIndex(db)
+	case "memory":
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		products, _, err := productRep.GetAll(repositories.ProductFilter{}, ctx)
+		if err != nil {
+			fatal(logger, "Failed to load products into search index", slog.Any("error", err))
+		}
+
+		index := search.NewMemoryIndex()
+		for i := range products {
+			if err := index.Index(&products[i], ctx); err != nil {
+				fatal(logger, "Failed to load products into search index", slog.Any("error", err))
+			}
+		}
+		logger.Info("Loaded products into in-memory search index", slog.Int("count", len(products)))
+		return index
+	default:
+		fatal(logger, "Unknown search backend", slog.String("search_backend", backend))
+		return nil
+	}
+}
+
+// appEnv returns APP_ENV, development or production. Production refuses settings that
+// are only meant for local development.
+func appEnv(logger *slog.Logger) string {
+	mode := env.GetEnvString("APP_ENV", "development")
+	if mode != "development" && mode != "production" {
+		fatal(logger, "Unknown app environment", slog.String("app_env", mode))
+	}
+
+	return mode
+}
+
+// newMailer returns the mailer of MAIL_BACKEND. The file and memory mailers never deliver
+// emails, so in production only smtp is accepted.
+func newMailer(logger *slog.Logger) mailer.Mailer {
+	backend := env.GetEnvString("MAIL_BACKEND", "file")
+	if appEnv(logger) == "production" && backend != "smtp" {
+		fatal(logger, "MAIL_BACKEND must be smtp in production", slog.String("mail_backend", backend))
+	}
+
+	switch backend {
+	case "smtp":
+		host := env.GetEnvString("SMTP_HOST", "localhost")
+		if host == "" {
+			fatal(logger, "SMTP_HOST must be set for the smtp mail backend")
+		}
+		return mailer.NewSMTPMailer(
+			host,
+			env.GetEnvInt("SMTP_PORT", 587),
+			env.GetEnvString("SMTP_USERNAME", ""),
+			env.GetEnvString("SMTP_PASSWORD", ""),
+			env.GetEnvString("MAIL_FROM", "no-reply@localhost"),
+		)
+	case "file":
+		return mailer.NewFileMailer(env.GetEnvString("MAIL_FILE", "mail.log"))
+	case "memory":
+		return mailer.NewMemoryMailer()
+	default:
+		fatal(logger, "Unknown mail backend", slog.String("mail_backend", backend))
+		return nil
+	}
+}
+
+func newRateLimitStore(logger *slog.Logger) ratelimit.Store {
+	backend := env.GetEnvString("RATE_LIMIT_BACKEND", "memory")
+	switch backend {
+	case "memory":
+		return ratelimit.NewMemoryStore()
+	default:
+		fatal(logger, "Unknown rate limit backend", slog.String("rate_limit_backend", backend))
+		return nil
+	}
 }
@@ -1,59 +1,332 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"shop/internal/env"
+	"shop/internal/jwtkeys"
+	"shop/internal/mailer"
+	"shop/internal/metrics"
+	"shop/internal/ratelimit"
 	"shop/internal/repositories"
+	"shop/internal/search"
 	"shop/internal/services"
+	"shop/migrations"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
 type Application struct {
-	port      int
-	jwtSecret string
+	port int
+	// metricsAddr is the address metrics are served on, apart from the API so that they
+	// aren't exposed with it. Metrics are not served if it is empty.
+	metricsAddr string
+	serviceName string
+	logger      *slog.Logger
+	metrics     *metrics.Metrics
+
+	// shutdownTimeout is how long in-flight requests are given to complete on shutdown.
+	shutdownTimeout time.Duration
+	// drainDelay is how long the application keeps accepting requests after reporting
+	// not ready, so load balancers notice it through the readiness probe first.
+	drainDelay time.Duration
+	// ready is true while the application accepts new traffic.
+	ready atomic.Bool
+	// readinessTimeout bounds the dependency checks of the readiness probe.
+	readinessTimeout time.Duration
+	// requestTimeout is the default deadline of API requests.
+	requestTimeout time.Duration
+	// mailRequestTimeout is the deadline of requests that send an email before responding.
+	mailRequestTimeout time.Duration
+
+	// trustedProxies are the addresses allowed to set X-Forwarded-For. Client IPs are
+	// taken from the connection otherwise, so they can't be spoofed to evade rate limits.
+	trustedProxies []string
+	rateLimitStore ratelimit.Store
+	// apiRateLimit applies to every API request per client IP, userRateLimit per
+	// authenticated user and authRateLimit to login, registration and the other
+	// endpoints that don't require a session, per client IP.
+	apiRateLimit  ratelimit.Limit
+	userRateLimit ratelimit.Limit
+	authRateLimit ratelimit.Limit
 
 	userRepo     repositories.UserRepository
 	productRepo  repositories.ProductRepository
 	cartRepo     repositories.CartRepository
 	cartItemRepo repositories.CartItemRepository
+	orderRepo    repositories.OrderRepository
+	categoryRepo repositories.CategoryRepository
+	tokenRepo    repositories.TokenRepository
+
+	passwordResetRepo     repositories.PasswordResetRepository
+	emailVerificationRepo repositories.EmailVerificationRepository
+	loginAttemptRepo      repositories.LoginAttemptRepository
+	accountUnlockRepo     repositories.AccountUnlockRepository
+	twoFactorRepo         repositories.TwoFactorRepository
+	apiKeyRepo            repositories.APIKeyRepository
+	schemaRepo            repositories.SchemaRepository
+
+	sellerApplicationRepo repositories.SellerApplicationRepository
+
+	// jwtKeys sign and verify access tokens. Their public halves are published as a JWKS.
+	jwtKeys *jwtkeys.KeySet
+
+	tokenService    *services.TokenService
+	userService     *services.UserService
+	cartService     *services.CartService
+	productService  *services.ProductService
+	orderService    *services.OrderService
+	categoryService *services.CategoryService
+	adminService    *services.AdminService
 
-	userService    *services.UserService
-	cartService    *services.CartService
-	productService *services.ProductService
+	sellerApplicationService *services.SellerApplicationService
+	passwordResetService     *services.PasswordResetService
+	emailVerificationService *services.EmailVerificationService
+	lockoutService           *services.LockoutService
+	twoFactorService         *services.TwoFactorService
+	apiKeyService            *services.APIKeyService
+	healthService            *services.HealthService
 }
 
 func GetApplication(
 	userRepo repositories.UserRepository,
 	productRepo repositories.ProductRepository,
 	cartRepo repositories.CartRepository,
-	cartItemRepo repositories.CartItemRepository) *Application {
+	cartItemRepo repositories.CartItemRepository,
+	orderRepo repositories.OrderRepository,
+	categoryRepo repositories.CategoryRepository,
+	sellerApplicationRepo repositories.SellerApplicationRepository,
+	tokenRepo repositories.TokenRepository,
+	passwordResetRepo repositories.PasswordResetRepository,
+	emailVerificationRepo repositories.EmailVerificationRepository,
+	loginAttemptRepo repositories.LoginAttemptRepository,
+	accountUnlockRepo repositories.AccountUnlockRepository,
+	twoFactorRepo repositories.TwoFactorRepository,
+	apiKeyRepo repositories.APIKeyRepository,
+	schemaRepo repositories.SchemaRepository,
+	searchIndex search.SearchIndex,
+	jwtKeys *jwtkeys.KeySet,
+	mailer mailer.Mailer,
+	logger *slog.Logger,
+	metrics *metrics.Metrics,
+	rateLimitStore ratelimit.Store) *Application {
+
+	tokenService := services.NewTokenService(
+		tokenRepo,
+		userRepo,
+		jwtKeys,
+		env.GetEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		env.GetEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+	)
+
+	appURL := env.GetEnvString("APP_URL", "http://localhost:8080")
+	emailVerificationService := services.NewEmailVerificationService(
+		emailVerificationRepo,
+		mailer,
+		appURL,
+		env.GetEnvDuration("EMAIL_VERIFICATION_TOKEN_TTL", 24*time.Hour),
+		env.GetEnvDuration("EMAIL_VERIFICATION_RESEND_INTERVAL", time.Minute),
+	)
+
+	lockoutService := services.NewLockoutService(
+		loginAttemptRepo,
+		accountUnlockRepo,
+		mailer,
+		appURL,
+		env.GetEnvDuration("ACCOUNT_UNLOCK_TOKEN_TTL", time.Hour),
+		services.LockoutPolicy{
+			MaxFailures:   env.GetEnvInt("LOGIN_MAX_FAILURES", 5),
+			Window:        env.GetEnvDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+			LockDuration:  env.GetEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+			MaxIPFailures: env.GetEnvInt("LOGIN_MAX_IP_FAILURES", 50),
+			BaseDelay:     env.GetEnvDuration("LOGIN_DELAY_BASE", time.Second),
+			MaxDelay:      env.GetEnvDuration("LOGIN_DELAY_MAX", 30*time.Second),
+		},
+		logger,
+	)
+	twoFactorService := services.NewTwoFactorService(
+		twoFactorRepo,
+		userRepo,
+		tokenService,
+		lockoutService,
+		env.GetEnvString("TOTP_ISSUER", "Shop"),
+		env.GetEnvDuration("LOGIN_CHALLENGE_TTL", 5*time.Minute),
+	)
+
+	app := &Application{
+		port:        env.GetEnvInt("PORT", 8080),
+		metricsAddr: env.GetEnvString("METRICS_ADDR", ":9090"),
+		serviceName: env.GetEnvString("OTEL_SERVICE_NAME", "shop"),
+		logger:      logger,
+		metrics:     metrics,
+
+		shutdownTimeout:  env.GetEnvDuration("SHUTDOWN_TIMEOUT", 15*time.Second),
+		drainDelay:       env.GetEnvDuration("SHUTDOWN_DRAIN_DELAY", 5*time.Second),
+		readinessTimeout: env.GetEnvDuration("READINESS_TIMEOUT", 2*time.Second),
 
-	return &Application{
-		port:      env.GetEnvInt("PORT", 8080),
-		jwtSecret: env.GetEnvString("JWT_SECRET", "some_secret"),
+		requestTimeout:     env.GetEnvDuration("REQUEST_TIMEOUT", 3*time.Second),
+		mailRequestTimeout: env.GetEnvDuration("MAIL_REQUEST_TIMEOUT", 10*time.Second),
+
+		trustedProxies: trustedProxies(),
+		rateLimitStore: rateLimitStore,
+		apiRateLimit:   rateLimitFromEnv("RATE_LIMIT_API", ratelimit.Limit{Requests: 300, Period: time.Minute}),
+		userRateLimit:  rateLimitFromEnv("RATE_LIMIT_USER", ratelimit.Limit{Requests: 600, Period: time.Minute}),
+		authRateLimit:  rateLimitFromEnv("RATE_LIMIT_AUTH", ratelimit.Limit{Requests: 10, Period: time.Minute}),
 
 		userRepo:     userRepo,
 		productRepo:  productRepo,
 		cartRepo:     cartRepo,
 		cartItemRepo: cartItemRepo,
+		orderRepo:    orderRepo,
+		categoryRepo: categoryRepo,
+		tokenRepo:    tokenRepo,
+
+		passwordResetRepo:     passwordResetRepo,
+		emailVerificationRepo: emailVerificationRepo,
+		loginAttemptRepo:      loginAttemptRepo,
+		accountUnlockRepo:     accountUnlockRepo,
+		twoFactorRepo:         twoFactorRepo,
+		apiKeyRepo:            apiKeyRepo,
+		schemaRepo:            schemaRepo,
+
+		sellerApplicationRepo: sellerApplicationRepo,
+
+		jwtKeys: jwtKeys,
+
+		tokenService:    tokenService,
+		userService:     services.NewUserService(userRepo, cartRepo, tokenService, emailVerificationService, lockoutService, twoFactorService, logger),
+		cartService:     services.NewCartService(cartRepo, cartItemRepo, productRepo),
+		productService:  services.NewProductService(productRepo, categoryRepo, searchIndex, logger),
+		orderService:    services.NewOrderService(orderRepo, metrics),
+		categoryService: services.NewCategoryService(categoryRepo),
+		adminService:    services.NewAdminService(userRepo, cartRepo, loginAttemptRepo),
 
-		userService:    services.NewUserService(userRepo, cartRepo, env.GetEnvString("JWT_SECRET", "some_secret")),
-		cartService:    services.NewCartService(cartRepo, cartItemRepo),
-		productService: services.NewProductService(productRepo),
+		sellerApplicationService: services.NewSellerApplicationService(sellerApplicationRepo),
+		passwordResetService: services.NewPasswordResetService(
+			userRepo, passwordResetRepo, mailer, appURL, env.GetEnvDuration("PASSWORD_RESET_TOKEN_TTL", time.Hour), logger,
+		),
+		emailVerificationService: emailVerificationService,
+		lockoutService:           lockoutService,
+		twoFactorService:         twoFactorService,
+		apiKeyService:            services.NewAPIKeyService(apiKeyRepo, userRepo),
 	}
+	app.healthService = services.NewHealthService(schemaRepo, migrations.Version, app.Ready, logger)
+
+	return app
 }
 
-func (app *Application) Serve() error {
-	r := GetRouter(app)
+// Serve handles requests until ctx is done. Then it marks the application as not ready,
+// keeps serving for the drain delay so the readiness probe reports it, stops accepting
+// connections and waits up to the shutdown timeout for in-flight requests to complete.
+// Metrics are served on their own address until the API has shut down.
+func (app *Application) Serve(ctx context.Context) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", app.port))
+	if err != nil {
+		return err
+	}
 
+	if app.metricsAddr != "" {
+		metricsListener, err := net.Listen("tcp", app.metricsAddr)
+		if err != nil {
+			listener.Close()
+			return fmt.Errorf("failed to listen for metrics: %w", err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", app.metrics.Handler())
+		metricsServer := &http.Server{
+			Handler:     mux,
+			ReadTimeout: 10 * time.Second,
+			ErrorLog:    slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+		}
+		go func() {
+			if err := metricsServer.Serve(metricsListener); !errors.Is(err, http.ErrServerClosed) {
+				app.logger.Error("Metrics server failed", slog.Any("error", err))
+			}
+		}()
+		defer metricsServer.Close()
+		app.logger.Info("Serving metrics", slog.String("addr", metricsListener.Addr().String()))
+	}
+
+	return app.serve(ctx, listener, GetRouter(app).Route())
+}
+
+func (app *Application) serve(ctx context.Context, listener net.Listener, handler http.Handler) error {
 	server := http.Server{
-		Addr:         fmt.Sprintf(":%d", app.port),
-		Handler:      r.Route(),
+		Handler:      handler,
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
+		ErrorLog:     slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+	app.ready.Store(true)
+	app.logger.Info("Listening", slog.String("addr", listener.Addr().String()))
+
+	select {
+	case err := <-serveErr:
+		app.ready.Store(false)
+		return err
+	case <-ctx.Done():
+	}
+
+	app.ready.Store(false)
+	app.logger.Info("Shutting down, draining traffic", slog.Duration("delay", app.drainDelay))
+	select {
+	case err := <-serveErr:
+		return err
+	case <-time.After(app.drainDelay):
+	}
+
+	app.logger.Info("Waiting for in-flight requests", slog.Duration("timeout", app.shutdownTimeout))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), app.shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("graceful shutdown failed: %w", err)
+	}
+
+	return nil
+}
+
+// Ready reports whether the application accepts new traffic. It turns false as soon
+// as shutdown starts.
+func (app *Application) Ready() bool {
+	return app.ready.Load()
+}
+
+// rateLimitFromEnv reads a limit written as "<requests>/<period>", such as "10/1m". It
+// stops the application on a malformed value rather than silently applying the default.
+func rateLimitFromEnv(key string, defaultValue ratelimit.Limit) ratelimit.Limit {
+	value := env.GetEnvString(key, "")
+	if value == "" {
+		return defaultValue
+	}
+
+	limit, err := ratelimit.ParseLimit(value)
+	if err != nil {
+		env.Invalid(key, err)
+	}
+
+	return limit
+}
+
+// trustedProxies reads the comma separated list of proxy addresses or CIDRs in
+// TRUSTED_PROXIES. No proxy is trusted by default.
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(env.GetEnvString("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
 	}
 
-	return server.ListenAndServe()
+	return proxies
 }
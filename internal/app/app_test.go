@@ -0,0 +1,119 @@
+package app
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestServeCompletesInFlightRequests starts a request, shuts the application down while
+// it is being handled and checks that readiness is reported as lost while the server
+// still accepts connections, that the request completes and that Serve returns cleanly.
+func TestServeCompletesInFlightRequests(t *testing.T) {
+	app := &Application{
+		logger:          slog.New(slog.DiscardHandler),
+		shutdownTimeout: 5 * time.Second,
+		drainDelay:      300 * time.Millisecond,
+	}
+
+	started := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		// Block until shutdown has started, then take a while longer than the drain delay.
+		for app.Ready() {
+			time.Sleep(5 * time.Millisecond)
+		}
+		time.Sleep(2 * app.drainDelay)
+		_, _ = io.WriteString(w, "done")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !app.Ready() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	baseURL := "http://" + listener.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- app.serve(ctx, listener, mux)
+	}()
+
+	type response struct {
+		status int
+		body   string
+		err    error
+	}
+	slow := make(chan response, 1)
+	go func() {
+		resp, err := http.Get(baseURL + "/slow")
+		if err != nil {
+			slow <- response{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		slow <- response{status: resp.StatusCode, body: string(body), err: err}
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request was not handled")
+	}
+	cancel()
+
+	// New probes must see the application as not ready before it stops listening.
+	probe := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	deadline := time.Now().Add(app.drainDelay)
+	for {
+		resp, err := probe.Get(baseURL + "/readyz")
+		if err != nil {
+			t.Fatalf("readiness probe failed during the drain delay: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("readiness probe returned %d during the drain delay, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case resp := <-slow:
+		if resp.err != nil {
+			t.Fatalf("in-flight request failed: %v", resp.err)
+		}
+		if resp.status != http.StatusOK || resp.body != "done" {
+			t.Errorf("in-flight request got %d %q, want 200 \"done\"", resp.status, resp.body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("in-flight request did not complete")
+	}
+
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			t.Errorf("Serve returned %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after shutdown")
+	}
+
+	if _, err := probe.Get(baseURL + "/readyz"); err == nil {
+		t.Error("server still accepts connections after shutdown")
+	}
+}
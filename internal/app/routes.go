@@ -1,62 +1,199 @@
 package app
 
 import (
+	"fmt"
 	"net/http"
+	"shop/internal/apperr"
+	"shop/internal/dto"
 	"shop/internal/handlers"
 	"shop/internal/middleware"
+	"shop/internal/ratelimit"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 type Router struct {
-	jwtSecret string
+	serviceName string
 
-	userHandler    *handlers.UserHandler
-	productHandler *handlers.ProductHandler
-	cartHandler    *handlers.CartHandler
+	requestTimeout     time.Duration
+	mailRequestTimeout time.Duration
+
+	trustedProxies []string
+	apiRateLimit   ratelimit.Limit
+	userRateLimit  ratelimit.Limit
+	authRateLimit  ratelimit.Limit
+
+	userHandler     *handlers.UserHandler
+	productHandler  *handlers.ProductHandler
+	cartHandler     *handlers.CartHandler
+	orderHandler    *handlers.OrderHandler
+	categoryHandler *handlers.CategoryHandler
+	adminHandler    *handlers.AdminHandler
+
+	sellerApplicationHandler *handlers.SellerApplicationHandler
+	passwordResetHandler     *handlers.PasswordResetHandler
+	emailVerificationHandler *handlers.EmailVerificationHandler
+	accountUnlockHandler     *handlers.AccountUnlockHandler
+	twoFactorHandler         *handlers.TwoFactorHandler
+	apiKeyHandler            *handlers.APIKeyHandler
+	healthHandler            *handlers.HealthHandler
+	jwksHandler              *handlers.JWKSHandler
 
 	middleware *middleware.Middleware
 }
 
 func GetRouter(app *Application) *Router {
 	return &Router{
-		jwtSecret: app.jwtSecret,
+		serviceName: app.serviceName,
+
+		requestTimeout:     app.requestTimeout,
+		mailRequestTimeout: app.mailRequestTimeout,
 
-		userHandler:    handlers.NewUserHandler(app.userService),
-		productHandler: handlers.NewProductHandler(app.productRepo, app.userService, app.productService),
-		cartHandler:    handlers.NewCartHandler(app.cartRepo, app.cartItemRepo, app.productRepo, app.userService, app.cartService),
+		trustedProxies: app.trustedProxies,
+		apiRateLimit:   app.apiRateLimit,
+		userRateLimit:  app.userRateLimit,
+		authRateLimit:  app.authRateLimit,
 
-		middleware: middleware.GetMiddleware(app.jwtSecret, app.userRepo),
+		userHandler:     handlers.NewUserHandler(app.userService, app.tokenService),
+		productHandler:  handlers.NewProductHandler(app.productRepo, app.userService, app.productService),
+		cartHandler:     handlers.NewCartHandler(app.cartRepo, app.cartItemRepo, app.productRepo, app.userService, app.cartService, app.metrics),
+		orderHandler:    handlers.NewOrderHandler(app.userService, app.cartService, app.orderService),
+		categoryHandler: handlers.NewCategoryHandler(app.categoryRepo, app.categoryService, app.productService),
+		adminHandler:    handlers.NewAdminHandler(app.userService, app.adminService, app.orderService),
+
+		sellerApplicationHandler: handlers.NewSellerApplicationHandler(app.userService, app.sellerApplicationService),
+		passwordResetHandler:     handlers.NewPasswordResetHandler(app.passwordResetService),
+		emailVerificationHandler: handlers.NewEmailVerificationHandler(app.userService, app.emailVerificationService),
+		accountUnlockHandler:     handlers.NewAccountUnlockHandler(app.lockoutService),
+		twoFactorHandler:         handlers.NewTwoFactorHandler(app.userService, app.twoFactorService),
+		apiKeyHandler:            handlers.NewAPIKeyHandler(app.userService, app.apiKeyService),
+		healthHandler:            handlers.NewHealthHandler(app.healthService, app.readinessTimeout),
+		jwksHandler:              handlers.NewJWKSHandler(app.jwtKeys),
+
+		middleware: middleware.GetMiddleware(app.tokenService, app.apiKeyService, app.userRepo, app.logger, app.metrics, app.rateLimitStore),
 	}
 }
 
 func (r *Router) Route() http.Handler {
-	g := gin.Default()
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(apperr.FieldName)
+	}
+
+	g := gin.New()
+	if err := g.SetTrustedProxies(r.trustedProxies); err != nil {
+		panic(fmt.Sprintf("invalid trusted proxies: %v", err))
+	}
+	g.Use(otelgin.Middleware(r.serviceName, otelgin.WithGinFilter(traced)))
+	g.Use(r.middleware.RequestID(), r.middleware.Metrics(), r.middleware.AccessLog(), r.middleware.Recovery(), r.middleware.ErrorHandler())
+	g.NoRoute(r.middleware.NoRoute())
+
+	g.GET("/healthz", r.healthHandler.Liveness)
+	g.GET("/readyz", r.healthHandler.Readiness)
+	g.GET("/.well-known/jwks.json", r.jwksHandler.JWKS)
+
+	v1 := g.Group("/api/v1", r.middleware.Timeout(r.requestTimeout))
+
+	// Anonymous clients are limited per IP, and more strictly on the endpoints that
+	// check credentials or send emails. Authenticated ones are limited per user.
+	public := v1.Group("", r.middleware.RateLimit("api", r.apiRateLimit, middleware.ByIP))
+	authLimit := r.middleware.RateLimit("auth", r.authRateLimit, middleware.ByIP)
+	{
+		public.GET("/products", r.productHandler.GetAllProducts)
+		public.GET("/products/search", r.productHandler.SearchProducts)
+		public.GET("/products/:id", r.productHandler.GetProduct)
+		public.GET("/users/:id/products", r.productHandler.GetProductsBySeller)
+
+		public.GET("/categories", r.categoryHandler.GetCategories)
+		public.GET("/categories/:id", r.categoryHandler.GetCategory)
+		public.GET("/categories/:id/products", r.categoryHandler.GetCategoryProducts)
+
+		public.POST("/auth/register", authLimit, r.userHandler.Register)
+		public.POST("/auth/login", authLimit, r.userHandler.Login)
+		public.POST("/auth/login/2fa", authLimit, r.twoFactorHandler.Login)
+		public.POST("/auth/refresh", authLimit, r.userHandler.Refresh)
+		public.POST("/auth/password/forgot", authLimit, r.passwordResetHandler.ForgotPassword)
+		public.POST("/auth/password/reset", authLimit, r.passwordResetHandler.ResetPassword)
+		public.GET("/auth/verify", authLimit, r.emailVerificationHandler.VerifyEmail)
+		public.POST("/auth/unlock", authLimit, r.accountUnlockHandler.UnlockAccount)
+	}
+
+	userLimit := r.middleware.RateLimit("user", r.userRateLimit, middleware.ByUser)
+	verifiedOnly := r.middleware.RequireVerifiedEmail()
+	sellerOnly := r.middleware.RequireRole(dto.TypeSeller)
 
-	v1 := g.Group("/api/v1")
+	// Routes sellers need to sync their catalog from their own systems also accept API
+	// keys with the scope of the route instead of an access token.
+	keyGroup := v1.Group("/")
 	{
-		v1.GET("/products", r.productHandler.GetAllProducts)
-		v1.GET("/products/:id", r.productHandler.GetProduct)
-		v1.GET("/users/:id/products", r.productHandler.GetProductsBySeller)
+		productsWrite := r.middleware.APIKeyAuth(dto.ScopeProductsWrite)
+		keyGroup.POST("/products", productsWrite, userLimit, verifiedOnly, r.productHandler.CreateProduct)
+		keyGroup.PUT("/products/:id", productsWrite, userLimit, r.productHandler.UpdateProduct)
+		keyGroup.DELETE("/products/:id", productsWrite, userLimit, r.productHandler.DeleteProduct)
+		keyGroup.PATCH("/products/:id/stock", productsWrite, userLimit, r.productHandler.UpdateProductStock)
 
-		v1.POST("/auth/register", r.userHandler.Register)
-		v1.POST("/auth/login", r.userHandler.Login)
+		ordersRead := r.middleware.APIKeyAuth(dto.ScopeOrdersRead)
+		keyGroup.GET("/seller/orders", ordersRead, userLimit, sellerOnly, r.orderHandler.GetSellerOrders)
+		keyGroup.GET("/seller/orders/:id", ordersRead, userLimit, sellerOnly, r.orderHandler.GetOrder)
+		keyGroup.PATCH("/orders/:id/status", r.middleware.APIKeyAuth(dto.ScopeOrdersWrite), userLimit, r.orderHandler.UpdateOrderStatus)
 	}
 
 	authGroup := v1.Group("/")
-	authGroup.Use(r.middleware.AuthMiddleware())
+	authGroup.Use(r.middleware.AuthMiddleware(), userLimit)
 	{
-		authGroup.POST("/products", r.productHandler.CreateProduct)
-		authGroup.PUT("/products/:id", r.productHandler.UpdateProduct)
-		authGroup.DELETE("/products/:id", r.productHandler.DeleteProduct)
+		authGroup.POST("/auth/logout", r.userHandler.Logout)
+		authGroup.POST("/auth/logout-all", r.userHandler.LogoutAll)
+		authGroup.POST("/auth/verify/resend", r.middleware.Timeout(r.mailRequestTimeout), r.emailVerificationHandler.ResendVerification)
+		authGroup.POST("/auth/2fa/totp", r.twoFactorHandler.EnrollTOTP)
+		authGroup.POST("/auth/2fa/totp/confirm", r.twoFactorHandler.ConfirmTOTP)
+		authGroup.POST("/auth/2fa/totp/disable", r.twoFactorHandler.DisableTOTP)
+		authGroup.POST("/auth/2fa/recovery-codes", r.twoFactorHandler.RegenerateRecoveryCodes)
+
+		adminOnly := r.middleware.RequireRole(dto.TypeAdministrator)
+		authGroup.POST("/categories", adminOnly, r.categoryHandler.CreateCategory)
+		authGroup.PUT("/categories/:id", adminOnly, r.categoryHandler.UpdateCategory)
+		authGroup.DELETE("/categories/:id", adminOnly, r.categoryHandler.DeleteCategory)
 
 		authGroup.GET("/cart/item", r.cartHandler.GetCartItems)
 		authGroup.POST("/cart/item", r.cartHandler.AddCartItem)
 		authGroup.PATCH("/cart/item/:id", r.cartHandler.UpdateCartItemQuantity)
 		authGroup.DELETE("/cart/item/:id", r.cartHandler.DeleteCartItem)
 		authGroup.DELETE("/cart/item", r.cartHandler.DeleteAllCartItems)
+
+		authGroup.GET("/orders", r.orderHandler.GetOrders)
+		authGroup.GET("/orders/:id", r.orderHandler.GetOrder)
+		authGroup.POST("/orders/checkout", verifiedOnly, r.orderHandler.Checkout)
+
+		authGroup.GET("/seller-applications", r.sellerApplicationHandler.GetMyApplications)
+		authGroup.POST("/seller-applications", r.sellerApplicationHandler.Apply)
+
+		authGroup.GET("/api-keys", sellerOnly, r.apiKeyHandler.GetAPIKeys)
+		authGroup.POST("/api-keys", sellerOnly, r.apiKeyHandler.CreateAPIKey)
+		authGroup.DELETE("/api-keys/:id", sellerOnly, r.apiKeyHandler.RevokeAPIKey)
+	}
+
+	adminGroup := authGroup.Group("/admin")
+	adminGroup.Use(r.middleware.RequireRole(dto.TypeAdministrator))
+	{
+		adminGroup.GET("/users", r.adminHandler.GetUsers)
+		adminGroup.POST("/users", r.adminHandler.CreateUser)
+		adminGroup.PATCH("/users/:id/type", r.adminHandler.ChangeUserType)
+		adminGroup.POST("/users/:id/ban", r.adminHandler.BanUser)
+		adminGroup.DELETE("/users/:id/ban", r.adminHandler.UnbanUser)
+		adminGroup.DELETE("/users/:id/lock", r.adminHandler.UnlockUser)
+
+		adminGroup.DELETE("/products/:id", r.productHandler.DeleteProduct)
+
+		adminGroup.GET("/orders", r.adminHandler.GetOrders)
+
+		adminGroup.GET("/seller-applications", r.sellerApplicationHandler.GetApplications)
+		adminGroup.POST("/seller-applications/:id/approve", r.sellerApplicationHandler.ApproveApplication)
+		adminGroup.POST("/seller-applications/:id/reject", r.sellerApplicationHandler.RejectApplication)
 	}
 
 	g.GET("/swagger/*any", func(c *gin.Context) {
@@ -68,3 +205,13 @@ func (r *Router) Route() http.Handler {
 
 	return g
 }
+
+// traced leaves health probes out of traces.
+func traced(c *gin.Context) bool {
+	switch c.FullPath() {
+	case "/healthz", "/readyz":
+		return false
+	default:
+		return true
+	}
+}
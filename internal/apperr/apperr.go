@@ -0,0 +1,145 @@
+// Package apperr defines the errors services return to handlers. An Error carries
+// everything needed to render it to the client, while the underlying cause is only
+// logged.
+package apperr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// StatusClientClosedRequest is the non-standard status logged when the client goes away
+// before the response is ready.
+const StatusClientClosedRequest = 499
+
+// Code identifies the kind of an error. Clients branch on codes, so existing codes
+// must never change their meaning.
+type Code string
+
+const (
+	CodeInvalidRequest     Code = "invalid_request"
+	CodeValidationFailed   Code = "validation_failed"
+	CodeUnauthorized       Code = "unauthorized"
+	CodeInvalidCredentials Code = "invalid_credentials"
+	CodeInvalidToken       Code = "invalid_token"
+	CodeInvalidTwoFactor   Code = "invalid_two_factor_code"
+	CodeForbidden          Code = "forbidden"
+	CodeAccountBanned      Code = "account_banned"
+	CodeAccountLocked      Code = "account_locked"
+	CodeEmailNotVerified   Code = "email_not_verified"
+	CodeNotFound           Code = "not_found"
+	CodeConflict           Code = "conflict"
+	CodeEmailTaken         Code = "email_taken"
+	CodeCartEmpty          Code = "cart_empty"
+	CodeInsufficientStock  Code = "insufficient_stock"
+	CodeInvalidTransition  Code = "invalid_status_transition"
+	CodeTooManyRequests    Code = "too_many_requests"
+	CodeTimeout            Code = "timeout"
+	CodeClientClosed       Code = "client_closed_request"
+	CodeInternal           Code = "internal_error"
+)
+
+// FieldError describes why a single field of the request is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type Error struct {
+	Code    Code
+	Status  int
+	Message string
+	Fields  []FieldError
+	// RetryAfter tells the client how long to wait before repeating the request.
+	RetryAfter time.Duration
+	cause      error
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
+
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// WithCode returns a copy of the error with a more specific code.
+func (e *Error) WithCode(code Code) *Error {
+	copied := *e
+	copied.Code = code
+	return &copied
+}
+
+// WithRetryAfter returns a copy of the error that asks the client to wait d before
+// retrying.
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	copied := *e
+	copied.RetryAfter = d
+	return &copied
+}
+
+// WithCause returns a copy of the error that wraps cause. The cause is logged but
+// never shown to the client.
+func (e *Error) WithCause(cause error) *Error {
+	copied := *e
+	copied.cause = cause
+	return &copied
+}
+
+func New(status int, code Code, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message}
+}
+
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, CodeInvalidRequest, message)
+}
+
+func Unauthorized(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+func Conflict(message string) *Error {
+	return New(http.StatusConflict, CodeConflict, message)
+}
+
+func TooManyRequests(message string) *Error {
+	return New(http.StatusTooManyRequests, CodeTooManyRequests, message)
+}
+
+// Internal hides err behind a generic message.
+func Internal(err error) *Error {
+	return New(http.StatusInternalServerError, CodeInternal, "something went wrong").WithCause(err)
+}
+
+// From returns err as an *Error. Errors caused by the request context being done are
+// reported as a timeout or a closed request, other errors that are not application
+// errors are considered internal.
+func From(err error) *Error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return New(http.StatusGatewayTimeout, CodeTimeout, "request timed out").WithCause(err)
+	case errors.Is(err, context.Canceled):
+		return New(StatusClientClosedRequest, CodeClientClosed, "client closed request").WithCause(err)
+	}
+
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	return Internal(err)
+}
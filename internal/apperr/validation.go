@@ -0,0 +1,70 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Validation converts an error returned by gin binding into an application error.
+// Failed validation rules are reported per field, other errors mean the request
+// could not be decoded at all. Their details are logged but not shown to the client,
+// as they expose the internals of the decoder.
+func Validation(err error) *Error {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return BadRequest("request body is malformed").WithCause(err)
+	}
+
+	appErr := New(http.StatusBadRequest, CodeValidationFailed, "request is invalid")
+	for _, fieldErr := range validationErrors {
+		appErr.Fields = append(appErr.Fields, FieldError{
+			Field:   fieldErr.Field(),
+			Message: ruleMessage(fieldErr),
+		})
+	}
+
+	return appErr
+}
+
+// FieldName names struct fields in validation errors after their json or form tag,
+// so the reported fields match what the client sent. Register it with
+// validator.Validate.RegisterTagNameFunc.
+func FieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+
+	return field.Name
+}
+
+func ruleMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email"
+	case "min":
+		return fmt.Sprintf("must be at least %s", fieldErr.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s", fieldErr.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fieldErr.Param())
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", fieldErr.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fieldErr.Param())
+	default:
+		return "is invalid"
+	}
+}
@@ -0,0 +1,70 @@
+package dto
+
+import (
+	"shop/internal/models"
+	"strings"
+	"time"
+)
+
+// APIKeyScope is a group of routes an API key may be used for.
+type APIKeyScope string
+
+const (
+	ScopeProductsWrite APIKeyScope = "products:write"
+	ScopeOrdersRead    APIKeyScope = "orders:read"
+	ScopeOrdersWrite   APIKeyScope = "orders:write"
+)
+
+func (s APIKeyScope) String() string {
+	return string(s)
+}
+
+func (s APIKeyScope) IsValid() bool {
+	switch s {
+	case ScopeProductsWrite, ScopeOrdersRead, ScopeOrdersWrite:
+		return true
+	default:
+		return false
+	}
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+}
+
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedAPIKeyResponse holds the key in plain text. It is shown only once, only its
+// hash is stored.
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+func APIKeyToResp(key *models.APIKey) *APIKeyResponse {
+	return &APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     strings.Fields(key.Scopes),
+		LastUsedAt: key.LastUsedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+func APIKeysToResp(keys []models.APIKey) []APIKeyResponse {
+	resp := make([]APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		resp = append(resp, *APIKeyToResp(&keys[i]))
+	}
+
+	return resp
+}
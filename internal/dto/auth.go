@@ -4,13 +4,12 @@ type RegisterRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required,min=8"`
-	Type     string `json:"type" binding:"required"`
 }
 
 type RegisterResponse struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
-	Token    string `json:"token"`
+	TokenResponse
 }
 
 type LoginRequest struct {
@@ -18,6 +17,48 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required,min=8"`
 }
 
+// LoginResponse holds the token pair, or a challenge if the user has two-factor
+// authentication enabled. The challenge token is exchanged together with a code for
+// the token pair at /auth/login/2fa.
 type LoginResponse struct {
-	Token string `json:"token"`
+	*TokenResponse
+	TwoFactorRequired  bool   `json:"two_factor_required"`
+	ChallengeToken     string `json:"challenge_token,omitempty"`
+	ChallengeExpiresIn int    `json:"challenge_expires_in,omitempty"`
+}
+
+// TokenResponse holds a short-lived access token and the refresh token used to get a new one.
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+type VerifyEmailQuery struct {
+	Token string `form:"token" binding:"required"`
+}
+
+type UnlockAccountRequest struct {
+	Token string `json:"token" binding:"required"`
 }
@@ -0,0 +1,50 @@
+package dto
+
+import "shop/internal/models"
+
+type CreateUpdateCategoryRequest struct {
+	Name     string `json:"name" binding:"required,max=100"`
+	ParentID *uint  `json:"parent_id" binding:"omitempty,gt=0"`
+}
+
+type CategoryResponse struct {
+	ID       uint               `json:"id"`
+	Name     string             `json:"name"`
+	ParentID *uint              `json:"parent_id"`
+	Children []CategoryResponse `json:"children,omitempty"`
+}
+
+func CategoryToResp(category *models.Category) *CategoryResponse {
+	return &CategoryResponse{
+		ID:       category.ID,
+		Name:     category.Name,
+		ParentID: category.ParentID,
+	}
+}
+
+// CategoriesToTree arranges a flat list of categories into trees rooted at the
+// top-level categories.
+func CategoriesToTree(categories []models.Category) []CategoryResponse {
+	children := make(map[uint][]models.Category)
+	var roots []models.Category
+	for _, category := range categories {
+		if category.ParentID == nil {
+			roots = append(roots, category)
+			continue
+		}
+		children[*category.ParentID] = append(children[*category.ParentID], category)
+	}
+
+	var build func(nodes []models.Category) []CategoryResponse
+	build = func(nodes []models.Category) []CategoryResponse {
+		response := make([]CategoryResponse, 0, len(nodes))
+		for _, node := range nodes {
+			nodeResponse := CategoryToResp(&node)
+			nodeResponse.Children = build(children[node.ID])
+			response = append(response, *nodeResponse)
+		}
+		return response
+	}
+
+	return build(roots)
+}
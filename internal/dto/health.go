@@ -0,0 +1,23 @@
+package dto
+
+type HealthStatus string
+
+const (
+	HealthOK          HealthStatus = "ok"
+	HealthUnavailable HealthStatus = "unavailable"
+)
+
+// DependencyCheck is the result of checking a single dependency of the application.
+type DependencyCheck struct {
+	Status    HealthStatus `json:"status"`
+	LatencyMs int64        `json:"latency_ms,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	// Version and ExpectedVersion are set by the migrations check.
+	Version         uint `json:"version,omitempty"`
+	ExpectedVersion uint `json:"expected_version,omitempty"`
+}
+
+type HealthResponse struct {
+	Status HealthStatus               `json:"status"`
+	Checks map[string]DependencyCheck `json:"checks,omitempty"`
+}
@@ -0,0 +1,123 @@
+package dto
+
+import (
+	"shop/internal/models"
+	"time"
+)
+
+type OrderStatus string
+
+const (
+	StatusPending   OrderStatus = "pending"
+	StatusPaid      OrderStatus = "paid"
+	StatusShipped   OrderStatus = "shipped"
+	StatusDelivered OrderStatus = "delivered"
+	StatusCancelled OrderStatus = "cancelled"
+	StatusRefunded  OrderStatus = "refunded"
+)
+
+func (s OrderStatus) String() string {
+	return string(s)
+}
+
+func (s OrderStatus) IsValid() bool {
+	switch s {
+	case StatusPending, StatusPaid, StatusShipped, StatusDelivered, StatusCancelled, StatusRefunded:
+		return true
+	default:
+		return false
+	}
+}
+
+type AdminOrderListQuery struct {
+	PaginationQuery
+	UserID uint   `form:"user_id" binding:"omitempty,gt=0"`
+	Status string `form:"status"`
+}
+
+type SellerOrderListQuery struct {
+	PaginationQuery
+	Status string `form:"status"`
+}
+
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+type OrderStatusChangeResponse struct {
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ActorID    *uint     `json:"actor_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type OrderItemResponse struct {
+	ID        uint             `json:"id"`
+	ProductID uint             `json:"product_id"`
+	Quantity  int              `json:"quantity"`
+	Price     float64          `json:"price"`
+	Product   *ProductResponse `json:"product,omitempty"`
+}
+
+type OrderResponse struct {
+	ID         uint                `json:"id"`
+	UserID     uint                `json:"user_id"`
+	TotalPrice float64             `json:"total_price"`
+	Status     string              `json:"status"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+	Items      []OrderItemResponse `json:"items"`
+
+	History []OrderStatusChangeResponse `json:"history,omitempty"`
+}
+
+type OrderListResponse struct {
+	PageMeta
+	Items []OrderResponse `json:"items"`
+}
+
+func OrderToResp(order *models.Order) *OrderResponse {
+	items := make([]OrderItemResponse, 0, len(order.Items))
+	for _, item := range order.Items {
+		itemResponse := OrderItemResponse{
+			ID:        item.ID,
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			Price:     item.Price,
+		}
+		if item.Product.ID != 0 {
+			itemResponse.Product = ProductToResp(&item.Product)
+		}
+		items = append(items, itemResponse)
+	}
+
+	var history []OrderStatusChangeResponse
+	for _, change := range order.StatusHistory {
+		history = append(history, OrderStatusChangeResponse{
+			FromStatus: change.FromStatus,
+			ToStatus:   change.ToStatus,
+			ActorID:    change.ActorID,
+			CreatedAt:  change.CreatedAt,
+		})
+	}
+
+	return &OrderResponse{
+		ID:         order.ID,
+		UserID:     order.UserID,
+		TotalPrice: order.TotalPrice,
+		Status:     order.Status,
+		CreatedAt:  order.CreatedAt,
+		UpdatedAt:  order.UpdatedAt,
+		Items:      items,
+		History:    history,
+	}
+}
+
+func OrdersToListResp(orders []models.Order, meta PageMeta) *OrderListResponse {
+	items := make([]OrderResponse, 0, len(orders))
+	for i := range orders {
+		items = append(items, *OrderToResp(&orders[i]))
+	}
+
+	return &OrderListResponse{PageMeta: meta, Items: items}
+}
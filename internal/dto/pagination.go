@@ -0,0 +1,45 @@
+package dto
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+type PaginationQuery struct {
+	Page     int `form:"page" binding:"omitempty,min=1"`
+	PageSize int `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+// Normalize fills in defaults for the omitted pagination parameters.
+func (p *PaginationQuery) Normalize() {
+	if p.Page <= 0 {
+		p.Page = 1
+	}
+	if p.PageSize <= 0 {
+		p.PageSize = DefaultPageSize
+	}
+	if p.PageSize > MaxPageSize {
+		p.PageSize = MaxPageSize
+	}
+}
+
+func (p PaginationQuery) Offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
+type PageMeta struct {
+	Page     int   `json:"page"`
+	PageSize int   `json:"page_size"`
+	Total    int64 `json:"total"`
+	NextPage *int  `json:"next_page"`
+}
+
+func NewPageMeta(query PaginationQuery, total int64) PageMeta {
+	meta := PageMeta{Page: query.Page, PageSize: query.PageSize, Total: total}
+	if int64(query.Page*query.PageSize) < total {
+		next := query.Page + 1
+		meta.NextPage = &next
+	}
+
+	return meta
+}
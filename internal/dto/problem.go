@@ -0,0 +1,41 @@
+package dto
+
+import (
+	"net/http"
+	"shop/internal/apperr"
+)
+
+// Problem is an RFC 7807 problem details object. Code is an extension member with the
+// stable error code clients can branch on, TraceID identifies the request trace to
+// quote when reporting a problem.
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Code     apperr.Code         `json:"code"`
+	Errors   []apperr.FieldError `json:"errors,omitempty"`
+	TraceID  string              `json:"trace_id,omitempty"`
+}
+
+func ErrorToProblem(err *apperr.Error, instance, traceID string) *Problem {
+	return &Problem{
+		Type:     "about:blank",
+		Title:    statusText(err.Status),
+		Status:   err.Status,
+		Detail:   err.Message,
+		Instance: instance,
+		Code:     err.Code,
+		Errors:   err.Fields,
+		TraceID:  traceID,
+	}
+}
+
+func statusText(status int) string {
+	if status == apperr.StatusClientClosedRequest {
+		return "Client Closed Request"
+	}
+
+	return http.StatusText(status)
+}
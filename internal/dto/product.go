@@ -13,6 +13,37 @@ type CreateUpdateProductRequest struct {
 	CategoryID  uint    `json:"category_id" binding:"required,gt=0"`
 }
 
+// CreateProductRequest is CreateUpdateProductRequest with the initial stock. Stock of
+// existing products changes through UpdateStockRequest.
+type CreateProductRequest struct {
+	CreateUpdateProductRequest
+	Stock int `json:"stock" binding:"gte=0"`
+}
+
+// UpdateStockRequest either sets the stock or adjusts it by a quantity. Orders change the
+// stock concurrently, so setting it should come with the stock it was based on in
+// ExpectedStock. The stock is then only set if it hasn't changed in the meantime.
+type UpdateStockRequest struct {
+	Stock         *int `json:"stock" binding:"required_without=Adjustment,excluded_with=Adjustment,omitempty,gte=0"`
+	ExpectedStock *int `json:"expected_stock" binding:"excluded_with=Adjustment,omitempty,gte=0"`
+	Adjustment    *int `json:"adjustment" binding:"omitempty,ne=0"`
+}
+
+type ProductListQuery struct {
+	PaginationQuery
+	CategoryID uint     `form:"category_id" binding:"omitempty,gt=0"`
+	SellerID   uint     `form:"seller_id" binding:"omitempty,gt=0"`
+	MinPrice   *float64 `form:"min_price" binding:"omitempty,gte=0"`
+	MaxPrice   *float64 `form:"max_price" binding:"omitempty,gte=0"`
+	Sort       string   `form:"sort" binding:"omitempty,oneof=price created_at name"`
+	Direction  string   `form:"direction" binding:"omitempty,oneof=asc desc"`
+}
+
+type ProductSearchQuery struct {
+	PaginationQuery
+	Q string `form:"q" binding:"required,max=200"`
+}
+
 type ProductResponse struct {
 	ID          uint      `json:"id"`
 	Name        string    `json:"name"`
@@ -21,6 +52,7 @@ type ProductResponse struct {
 	ImageUrl    string    `json:"image_url"`
 	CategoryID  uint      `json:"category_id"`
 	UserID      uint      `json:"user_id"`
+	Stock       int       `json:"stock"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
@@ -33,6 +65,32 @@ func ProductToResp(product *models.Product) *ProductResponse {
 		ImageUrl:    product.ImageUrl,
 		CategoryID:  product.CategoryID,
 		UserID:      product.UserID,
+		Stock:       product.Stock,
 		CreatedAt:   product.CreatedAt,
 	}
 }
+
+func ProductsToResp(products []models.Product) []ProductResponse {
+	response := make([]ProductResponse, 0, len(products))
+	for i := range products {
+		response = append(response, *ProductToResp(&products[i]))
+	}
+
+	return response
+}
+
+type ProductListResponse struct {
+	PageMeta
+	Items []ProductResponse `json:"items"`
+}
+
+type ProductSearchHit struct {
+	ProductResponse
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+type ProductSearchResponse struct {
+	PageMeta
+	Items []ProductSearchHit `json:"items"`
+}
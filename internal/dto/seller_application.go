@@ -0,0 +1,72 @@
+package dto
+
+import (
+	"shop/internal/models"
+	"time"
+)
+
+type SellerApplicationStatus string
+
+const (
+	ApplicationPending  SellerApplicationStatus = "pending"
+	ApplicationApproved SellerApplicationStatus = "approved"
+	ApplicationRejected SellerApplicationStatus = "rejected"
+)
+
+func (s SellerApplicationStatus) String() string {
+	return string(s)
+}
+
+func (s SellerApplicationStatus) IsValid() bool {
+	switch s {
+	case ApplicationPending, ApplicationApproved, ApplicationRejected:
+		return true
+	default:
+		return false
+	}
+}
+
+type SellerApplicationRequest struct {
+	Message string `json:"message" binding:"max=500"`
+}
+
+type SellerApplicationListQuery struct {
+	PaginationQuery
+	Status string `form:"status"`
+}
+
+type SellerApplicationResponse struct {
+	ID         uint       `json:"id"`
+	UserID     uint       `json:"user_id"`
+	Message    string     `json:"message"`
+	Status     string     `json:"status"`
+	ReviewerID *uint      `json:"reviewer_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ReviewedAt *time.Time `json:"reviewed_at"`
+}
+
+type SellerApplicationListResponse struct {
+	PageMeta
+	Items []SellerApplicationResponse `json:"items"`
+}
+
+func SellerApplicationToResp(application *models.SellerApplication) *SellerApplicationResponse {
+	return &SellerApplicationResponse{
+		ID:         application.ID,
+		UserID:     application.UserID,
+		Message:    application.Message,
+		Status:     application.Status,
+		ReviewerID: application.ReviewerID,
+		CreatedAt:  application.CreatedAt,
+		ReviewedAt: application.ReviewedAt,
+	}
+}
+
+func SellerApplicationsToListResp(applications []models.SellerApplication, meta PageMeta) *SellerApplicationListResponse {
+	items := make([]SellerApplicationResponse, 0, len(applications))
+	for i := range applications {
+		items = append(items, *SellerApplicationToResp(&applications[i]))
+	}
+
+	return &SellerApplicationListResponse{PageMeta: meta, Items: items}
+}
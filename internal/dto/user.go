@@ -1,5 +1,10 @@
 package dto
 
+import (
+	"shop/internal/models"
+	"time"
+)
+
 type UserType string
 
 const (
@@ -20,3 +25,60 @@ func (t UserType) IsValid() bool {
 		return false
 	}
 }
+
+type CreateUserRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+	Type     string `json:"type" binding:"required"`
+}
+
+type UserListQuery struct {
+	PaginationQuery
+	Q    string `form:"q" binding:"max=100"`
+	Type string `form:"type"`
+	// Locked keeps only users whose login is locked out after too many failures.
+	Locked bool `form:"locked"`
+}
+
+type ChangeUserTypeRequest struct {
+	Type string `json:"type" binding:"required"`
+}
+
+type UserResponse struct {
+	ID       uint       `json:"id"`
+	Username string     `json:"username"`
+	Email    string     `json:"email"`
+	Type     string     `json:"type"`
+	BannedAt *time.Time `json:"banned_at"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	LockedUntil     *time.Time `json:"locked_until"`
+}
+
+type UserListResponse struct {
+	PageMeta
+	Items []UserResponse `json:"items"`
+}
+
+func UserToResp(user *models.User) *UserResponse {
+	return &UserResponse{
+		ID:       user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		Type:     user.Type,
+		BannedAt: user.BannedAt,
+
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		LockedUntil:     user.LockedUntil,
+	}
+}
+
+func UsersToListResp(users []models.User, meta PageMeta) *UserListResponse {
+	items := make([]UserResponse, 0, len(users))
+	for i := range users {
+		items = append(items, *UserToResp(&users[i]))
+	}
+
+	return &UserListResponse{PageMeta: meta, Items: items}
+}
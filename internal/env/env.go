@@ -1,8 +1,11 @@
 package env
 
 import (
+	"errors"
+	"log/slog"
 	"os"
 	"strconv"
+	"time"
 )
 
 func GetEnvString(key, defaultValue string) string {
@@ -21,3 +24,30 @@ func GetEnvInt(key string, defaultValue int) int {
 
 	return defaultValue
 }
+
+// GetEnvDuration reads a duration such as "30s". An unset or empty variable yields the
+// default, while an unparsable or negative value stops the application: falling back
+// to the default would hide a typo such as "30" for "30s".
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		Invalid(key, err)
+	}
+	if duration < 0 {
+		Invalid(key, errors.New("duration must not be negative"))
+	}
+
+	return duration
+}
+
+// Invalid logs that the variable key holds an unusable value and exits. It is meant for
+// settings read at startup, before the application serves any request.
+func Invalid(key string, err error) {
+	slog.Error("Invalid environment variable", slog.String("key", key), slog.Any("error", err))
+	os.Exit(1)
+}
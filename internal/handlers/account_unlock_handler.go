@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccountUnlockHandler struct {
+	lockoutService *services.LockoutService
+}
+
+// UnlockAccount lifts the login lockout of an account
+// @Summary Unlock account
+// @Description Lift the lockout of an account after too many failed logins using the token from the unlock link emailed to its owner. The token can be used once.
+// @Tags Auth
+// @Accept json
+// @Param request body dto.UnlockAccountRequest true "Unlock token"
+// @Success 204 "Account unlocked"
+// @Failure 400 {object} dto.Problem "Invalid input or invalid/expired token"
+// @Failure 429 {object} dto.Problem "Too many requests"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Router /api/v1/auth/unlock [post]
+func (ah *AccountUnlockHandler) UnlockAccount(c *gin.Context) {
+	var req dto.UnlockAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := ah.lockoutService.Unlock(req, ctx); err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func NewAccountUnlockHandler(lockoutService *services.LockoutService) *AccountUnlockHandler {
+	return &AccountUnlockHandler{lockoutService: lockoutService}
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"net/http"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	userService  *services.UserService
+	adminService *services.AdminService
+	orderService *services.OrderService
+}
+
+// GetUsers return users
+// @Summary Returns users
+// @Description Returns a page of users matching the query by username or email. Available for administrators only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param q query string false "Part of username or email"
+// @Param type query string false "User type" Enums(customer, seller, administrator)
+// @Param locked query bool false "Only users whose login is locked out"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} dto.UserListResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users [get]
+func (ah *AdminHandler) GetUsers(c *gin.Context) {
+	var query dto.UserListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+	query.Normalize()
+
+	ctx := c.Request.Context()
+	users, total, err := ah.adminService.GetUsers(query, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.UsersToListResp(users, dto.NewPageMeta(query.PaginationQuery, total)))
+}
+
+// CreateUser create user
+// @Summary Creates user
+// @Description Creates user of any type, including administrators. Available for administrators only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param user body dto.CreateUserRequest true "User data"
+// @Success 201 {object} dto.UserResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 409 {object} dto.Problem "Email is already registered"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users [post]
+func (ah *AdminHandler) CreateUser(c *gin.Context) {
+	var createReq dto.CreateUserRequest
+	if err := c.ShouldBindJSON(&createReq); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := ah.userService.CreateUser(createReq, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.UserToResp(user))
+}
+
+// ChangeUserType change user type
+// @Summary Changes user type
+// @Description Changes user type. Available for administrators only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path uint true "User ID"
+// @Param type body dto.ChangeUserTypeRequest true "New user type"
+// @Success 200 {object} dto.UserResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users/{id}/type [patch]
+func (ah *AdminHandler) ChangeUserType(c *gin.Context) {
+	id, err := idParam(c, "id")
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	var typeReq dto.ChangeUserTypeRequest
+	if err := c.ShouldBindJSON(&typeReq); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+	admin, err := ah.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := ah.adminService.ChangeUserType(id, dto.UserType(typeReq.Type), admin, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.UserToResp(user))
+}
+
+// BanUser ban user
+// @Summary Bans user
+// @Description Bans user account, so it can't log in or use issued tokens. Available for administrators only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path uint true "User ID"
+// @Success 200 {object} dto.UserResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users/{id}/ban [post]
+func (ah *AdminHandler) BanUser(c *gin.Context) {
+	ah.setBanned(c, true)
+}
+
+// UnbanUser unban user
+// @Summary Unbans user
+// @Description Lifts the ban from user account. Available for administrators only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path uint true "User ID"
+// @Success 200 {object} dto.UserResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users/{id}/ban [delete]
+func (ah *AdminHandler) UnbanUser(c *gin.Context) {
+	ah.setBanned(c, false)
+}
+
+// UnlockUser lifts the login lockout of user
+// @Summary Unlocks user login
+// @Description Lifts the lockout of user account after too many failed logins and forgets the failures. Available for administrators only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path uint true "User ID"
+// @Success 200 {object} dto.UserResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users/{id}/lock [delete]
+func (ah *AdminHandler) UnlockUser(c *gin.Context) {
+	id, err := idParam(c, "id")
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := ah.adminService.UnlockUser(id, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.UserToResp(user))
+}
+
+// GetOrders return all orders
+// @Summary Returns all orders
+// @Description Returns a page of orders of all users, newest first. Available for administrators only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param user_id query int false "User ID"
+// @Param status query string false "Order status" Enums(pending, paid, shipped, delivered, cancelled, refunded)
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} dto.OrderListResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/orders [get]
+func (ah *AdminHandler) GetOrders(c *gin.Context) {
+	var query dto.AdminOrderListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+	query.Normalize()
+
+	ctx := c.Request.Context()
+	orders, total, err := ah.orderService.GetAllOrders(query, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.OrdersToListResp(orders, dto.NewPageMeta(query.PaginationQuery, total)))
+}
+
+func (ah *AdminHandler) setBanned(c *gin.Context, banned bool) {
+	id, err := idParam(c, "id")
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	admin, err := ah.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := ah.adminService.SetBanned(id, banned, admin, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.UserToResp(user))
+}
+
+func NewAdminHandler(
+	userService *services.UserService,
+	adminService *services.AdminService,
+	orderService *services.OrderService,
+) *AdminHandler {
+	return &AdminHandler{
+		userService:  userService,
+		adminService: adminService,
+		orderService: orderService,
+	}
+}
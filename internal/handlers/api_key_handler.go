@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	userService   *services.UserService
+	apiKeyService *services.APIKeyService
+}
+
+// GetAPIKeys return seller API keys
+// @Summary Returns API keys
+// @Description Returns the active API keys of the current seller, newest first. Keys themselves are never shown again, only their prefixes
+// @Tags API keys
+// @Accept json
+// @Produce json
+// @Success 200 {array} dto.APIKeyResponse
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/api-keys [get]
+func (ah *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	user, err := ah.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	keys, err := ah.apiKeyService.GetUserKeys(user, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.APIKeysToResp(keys))
+}
+
+// CreateAPIKey create seller API key
+// @Summary Creates API key
+// @Description Creates an API key the seller's own systems can use instead of an access token, on the routes its scopes allow. The key is returned only once. Available for sellers only
+// @Tags API keys
+// @Accept json
+// @Produce json
+// @Param key body dto.CreateAPIKeyRequest true "Key name and scopes (products:write, orders:read, orders:write)"
+// @Success 201 {object} dto.CreatedAPIKeyResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/api-keys [post]
+func (ah *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req dto.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+	user, err := ah.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	key, err := ah.apiKeyService.Create(user, req, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// RevokeAPIKey revoke seller API key
+// @Summary Revokes API key
+// @Description Revokes an API key of the current seller. Requests with it are refused from then on
+// @Tags API keys
+// @Accept json
+// @Produce json
+// @Param id path uint true "API key ID"
+// @Success 204 "API key revoked"
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/api-keys/{id} [delete]
+func (ah *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := idParam(c, "id")
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	user, err := ah.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := ah.apiKeyService.Revoke(user, id, ctx); err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func NewAPIKeyHandler(userService *services.UserService, apiKeyService *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{userService: userService, apiKeyService: apiKeyService}
+}
@@ -1,13 +1,13 @@
 package handlers
 
 import (
-	"context"
 	"net/http"
+	"shop/internal/apperr"
 	"shop/internal/dto"
+	"shop/internal/metrics"
 	"shop/internal/models"
 	"shop/internal/repositories"
 	"shop/internal/services"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -19,6 +19,7 @@ type CartHandler struct {
 	productRepository  repositories.ProductRepository
 	userService        *services.UserService
 	cartService        *services.CartService
+	metrics            *metrics.Metrics
 }
 
 // AddCartItem add product to cart
@@ -29,10 +30,11 @@ type CartHandler struct {
 // @Produce json
 // @Param credentials body dto.CartItemRequest true "Data for add item to cart"
 // @Success 201 {object} dto.CartItemResponse
-// @Failure 400 {object} map[string]string "Bad request"
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 403 {object} map[string]string "Forbidden"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 409 {object} dto.Problem "Not enough stock"
+// @Failure 500 {object} dto.Problem "Internal server error"
 // @Security ApiKeyAuth
 // @Router /api/v1/cart/item [post]
 func (ch *CartHandler) AddCartItem(c *gin.Context) {
@@ -43,21 +45,18 @@ func (ch *CartHandler) AddCartItem(c *gin.Context) {
 
 	var itemReq dto.CartItemRequest
 	if err := c.ShouldBindJSON(&itemReq); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abort(c, apperr.Validation(err))
 		return
 	}
-	productCtx, productCancel := context.WithTimeout(context.Background(), time.Second*3)
-	defer productCancel()
-	if _, err := ch.productRepository.GetProduct(itemReq.ProductID, productCtx); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	ctx := c.Request.Context()
+	cart, err := ch.cartRepository.Get(user.ID, ctx)
+	if err != nil {
+		abort(c, apperr.Internal(err))
 		return
 	}
 
-	cartCtx, cartCancel := context.WithTimeout(context.Background(), time.Second*3)
-	defer cartCancel()
-	cart, err := ch.cartRepository.Get(user.ID, cartCtx)
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+	if err := ch.cartService.CheckStock(cart.ID, itemReq.ProductID, itemReq.Quantity, 0, ctx); err != nil {
+		abort(c, err)
 		return
 	}
 
@@ -68,13 +67,12 @@ func (ch *CartHandler) AddCartItem(c *gin.Context) {
 		CreatedAt: time.Now(),
 	}
 
-	itemCtx, itemCancel := context.WithTimeout(context.Background(), time.Second*3)
-	defer itemCancel()
-	err = ch.cartItemRepository.Create(cartItem, itemCtx)
+	err = ch.cartItemRepository.Create(cartItem, ctx)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abort(c, apperr.Internal(err))
 		return
 	}
+	ch.metrics.CartUpdated(metrics.CartItemAdded)
 
 	itemResponse := dto.CartItemResponse{
 		ID:        cartItem.ID,
@@ -93,9 +91,9 @@ func (ch *CartHandler) AddCartItem(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 200 {object} []dto.CartItemResponse
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 403 {object} map[string]string "Forbidden"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 500 {object} dto.Problem "Internal server error"
 // @Security ApiKeyAuth
 // @Router /api/v1/cart/item [get]
 func (ch *CartHandler) GetCartItems(c *gin.Context) {
@@ -104,11 +102,10 @@ func (ch *CartHandler) GetCartItems(c *gin.Context) {
 		return
 	}
 
-	itemCtx, itemCancel := context.WithTimeout(context.Background(), time.Second*3)
-	defer itemCancel()
-	cartItems, err := ch.cartItemRepository.GetAllByCartID(user.Cart.ID, itemCtx)
+	ctx := c.Request.Context()
+	cartItems, err := ch.cartItemRepository.GetAllByCartID(user.Cart.ID, ctx)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abort(c, apperr.Internal(err))
 		return
 	}
 
@@ -135,11 +132,12 @@ func (ch *CartHandler) GetCartItems(c *gin.Context) {
 // @Param id path uint true "Cart item ID"
 // @Param Quantity body dto.CartItemUpdateRequest true "Item quantity"
 // @Success 200 {object} map[string]string "ok"
-// @Failure 400 {object} map[string]string "Bad request"
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 403 {object} map[string]string "Forbidden"
-// @Failure 404 {object} map[string]string "Not found"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 409 {object} dto.Problem "Not enough stock"
+// @Failure 500 {object} dto.Problem "Internal server error"
 // @Security ApiKeyAuth
 // @Router /api/v1/cart/item/{id} [patch]
 func (ch *CartHandler) UpdateCartItemQuantity(c *gin.Context) {
@@ -150,32 +148,36 @@ func (ch *CartHandler) UpdateCartItemQuantity(c *gin.Context) {
 
 	var itemReq dto.CartItemUpdateRequest
 	if err := c.ShouldBindJSON(&itemReq); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abort(c, apperr.Validation(err))
 		return
 	}
 	if itemReq.Quantity <= 0 {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Quantity must be greater than 0"})
+		abort(c, apperr.BadRequest("Quantity must be greater than 0"))
 		return
 	}
 
-	id, err := strconv.Atoi(c.Param("id"))
+	id, err := idParam(c, "id")
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	item, err := ch.cartService.CheckItemBelongsToCart(id, user, ctx)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abort(c, err)
 		return
 	}
-	checkCtx, checkCancel := context.WithTimeout(context.Background(), time.Second*3)
-	defer checkCancel()
-	if status, err := ch.cartService.CheckItemBelongsToCart(id, user, checkCtx); status != http.StatusOK {
-		c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+
+	if err := ch.cartService.CheckStock(item.CartID, item.ProductID, itemReq.Quantity, item.ID, ctx); err != nil {
+		abort(c, err)
 		return
 	}
 
-	itemCtx, itemCancel := context.WithTimeout(context.Background(), time.Second*3)
-	defer itemCancel()
-	if err := ch.cartItemRepository.UpdateQty(uint(id), itemReq.Quantity, itemCtx); err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := ch.cartItemRepository.UpdateQty(id, itemReq.Quantity, ctx); err != nil {
+		abort(c, apperr.Internal(err))
 		return
 	}
+	ch.metrics.CartUpdated(metrics.CartItemUpdated)
 
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
@@ -188,17 +190,17 @@ func (ch *CartHandler) UpdateCartItemQuantity(c *gin.Context) {
 // @Produce json
 // @Param id path uint true "Cart item ID"
 // @Success 204 {object} nil
-// @Failure 400 {object} map[string]string "Bad request"
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 403 {object} map[string]string "Forbidden"
-// @Failure 404 {object} map[string]string "Not found"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 500 {object} dto.Problem "Internal server error"
 // @Security ApiKeyAuth
 // @Router /api/v1/cart/item/{id} [delete]
 func (ch *CartHandler) DeleteCartItem(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
+	id, err := idParam(c, "id")
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abort(c, err)
 		return
 	}
 	user := ch.getValidatedUser(c)
@@ -206,19 +208,17 @@ func (ch *CartHandler) DeleteCartItem(c *gin.Context) {
 		return
 	}
 
-	checkCtx, checkCancel := context.WithTimeout(context.Background(), time.Second*3)
-	defer checkCancel()
-	if status, err := ch.cartService.CheckItemBelongsToCart(id, user, checkCtx); status != http.StatusOK {
-		c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+	ctx := c.Request.Context()
+	if _, err := ch.cartService.CheckItemBelongsToCart(id, user, ctx); err != nil {
+		abort(c, err)
 		return
 	}
 
-	deleteCtx, deleteCancel := context.WithTimeout(context.Background(), time.Second*3)
-	defer deleteCancel()
-	if err := ch.cartItemRepository.DeleteItem(uint(id), deleteCtx); err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := ch.cartItemRepository.DeleteItem(id, ctx); err != nil {
+		abort(c, apperr.Internal(err))
 		return
 	}
+	ch.metrics.CartUpdated(metrics.CartItemRemoved)
 
 	c.Status(http.StatusNoContent)
 }
@@ -230,10 +230,10 @@ func (ch *CartHandler) DeleteCartItem(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 204 {object} nil
-// @Failure 400 {object} map[string]string "Bad request"
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 403 {object} map[string]string "Forbidden"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 500 {object} dto.Problem "Internal server error"
 // @Security ApiKeyAuth
 // @Router /api/v1/cart/item [delete]
 func (ch *CartHandler) DeleteAllCartItems(c *gin.Context) {
@@ -242,25 +242,24 @@ func (ch *CartHandler) DeleteAllCartItems(c *gin.Context) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
-	defer cancel()
+	ctx := c.Request.Context()
 	if err := ch.cartItemRepository.DeleteAll(user.Cart.ID, ctx); err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abort(c, apperr.Internal(err))
 		return
 	}
+	ch.metrics.CartUpdated(metrics.CartCleared)
 
 	c.Status(http.StatusNoContent)
 }
 
 func (ch *CartHandler) getValidatedUser(c *gin.Context) *models.User {
-	user, status := ch.userService.GetUserFromContext(c)
-	if status == http.StatusUnauthorized {
-		c.AbortWithStatusJSON(status, gin.H{"error": "unauthorized"})
+	user, err := ch.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
 		return nil
 	}
-	status, err := ch.cartService.ValidateUser(user)
-	if err != nil {
-		c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+	if err := ch.cartService.ValidateUser(user); err != nil {
+		abort(c, err)
 		return nil
 	}
 
@@ -273,6 +272,7 @@ func NewCartHandler(
 	productRepository repositories.ProductRepository,
 	userService *services.UserService,
 	cartService *services.CartService,
+	metrics *metrics.Metrics,
 ) *CartHandler {
 
 	return &CartHandler{
@@ -281,5 +281,6 @@ func NewCartHandler(
 		productRepository:  productRepository,
 		userService:        userService,
 		cartService:        cartService,
+		metrics:            metrics,
 	}
 }
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"net/http"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/repositories"
+	"shop/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CategoryHandler struct {
+	categoryRepository repositories.CategoryRepository
+	categoryService    *services.CategoryService
+	productService     *services.ProductService
+}
+
+// GetCategories return category tree
+// @Summary Returns all categories
+// @Description Returns all categories arranged in a tree
+// @Tags Categories
+// @Accept json
+// @Produce json
+// @Success 200 {object} []dto.CategoryResponse
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Router /api/v1/categories [get]
+func (ch *CategoryHandler) GetCategories(c *gin.Context) {
+	ctx := c.Request.Context()
+	categories, err := ch.categoryRepository.GetAll(ctx)
+	if err != nil {
+		abort(c, apperr.Internal(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.CategoriesToTree(categories))
+}
+
+// GetCategory return category by id
+// @Summary Returns category by id
+// @Description Returns category by its id
+// @Tags Categories
+// @Accept json
+// @Produce json
+// @Param id path uint true "Category ID"
+// @Success 200 {object} dto.CategoryResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Router /api/v1/categories/{id} [get]
+func (ch *CategoryHandler) GetCategory(c *gin.Context) {
+	id, err := idParam(c, "id")
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	category, err := ch.categoryService.GetCategory(id, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.CategoryToResp(category))
+}
+
+// GetCategoryProducts return category products
+// @Summary Returns category products
+// @Description Returns products of the category including products of all its subcategories
+// @Tags Categories
+// @Accept json
+// @Produce json
+// @Param id path uint true "Category ID"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Param seller_id query int false "Seller ID"
+// @Param min_price query number false "Minimal price"
+// @Param max_price query number false "Maximal price"
+// @Param sort query string false "Sort field" Enums(price, created_at, name)
+// @Param direction query string false "Sort direction" Enums(asc, desc)
+// @Success 200 {object} dto.ProductListResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Router /api/v1/categories/{id}/products [get]
+func (ch *CategoryHandler) GetCategoryProducts(c *gin.Context) {
+	id, err := idParam(c, "id")
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	var query dto.ProductListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+	query.Normalize()
+	query.CategoryID = id
+
+	ctx := c.Request.Context()
+	products, total, err := ch.productService.GetProducts(query, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	response := dto.ProductListResponse{
+		PageMeta: dto.NewPageMeta(query.PaginationQuery, total),
+		Items:    dto.ProductsToResp(products),
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateCategory create category
+// @Summary Creates category
+// @Description Creates category and returns one. Available for administrators only
+// @Tags Categories
+// @Accept json
+// @Produce json
+// @Param category body dto.CreateUpdateCategoryRequest true "Data for create category"
+// @Success 201 {object} dto.CategoryResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 409 {object} dto.Problem "Conflict"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/categories [post]
+func (ch *CategoryHandler) CreateCategory(c *gin.Context) {
+	var createReq dto.CreateUpdateCategoryRequest
+	if err := c.ShouldBindJSON(&createReq); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	category, err := ch.categoryService.CreateCategory(createReq, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.CategoryToResp(category))
+}
+
+// UpdateCategory update category
+// @Summary Updates category
+// @Description Updates category name and parent. Available for administrators only
+// @Tags Categories
+// @Accept json
+// @Produce json
+// @Param id path uint true "Category ID"
+// @Param category body dto.CreateUpdateCategoryRequest true "Data for update category"
+// @Success 200 {object} dto.CategoryResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 409 {object} dto.Problem "Conflict"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/categories/{id} [put]
+func (ch *CategoryHandler) UpdateCategory(c *gin.Context) {
+	id, err := idParam(c, "id")
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	var updateReq dto.CreateUpdateCategoryRequest
+	if err := c.ShouldBindJSON(&updateReq); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	category, err := ch.categoryService.UpdateCategory(id, updateReq, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.CategoryToResp(category))
+}
+
+// DeleteCategory delete category
+// @Summary Deletes category
+// @Description Deletes category without subcategories and products. Available for administrators only
+// @Tags Categories
+// @Accept json
+// @Produce json
+// @Param id path uint true "Category ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 409 {object} dto.Problem "Conflict"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/categories/{id} [delete]
+func (ch *CategoryHandler) DeleteCategory(c *gin.Context) {
+	id, err := idParam(c, "id")
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	if err := ch.categoryService.DeleteCategory(id, ctx); err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func NewCategoryHandler(
+	categoryRepository repositories.CategoryRepository,
+	categoryService *services.CategoryService,
+	productService *services.ProductService,
+) *CategoryHandler {
+	return &CategoryHandler{
+		categoryRepository: categoryRepository,
+		categoryService:    categoryService,
+		productService:     productService,
+	}
+}
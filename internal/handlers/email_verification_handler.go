@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EmailVerificationHandler struct {
+	userService         *services.UserService
+	verificationService *services.EmailVerificationService
+}
+
+// VerifyEmail confirms the email of a user
+// @Summary Verify email
+// @Description Confirm the email of the user the verification link was sent to.
+// @Tags Auth
+// @Produce json
+// @Param token query string true "Token from the verification link"
+// @Success 200 {object} dto.MessageResponse "Email verified"
+// @Failure 400 {object} dto.Problem "Invalid or expired token"
+// @Failure 429 {object} dto.Problem "Too many requests"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Router /api/v1/auth/verify [get]
+func (eh *EmailVerificationHandler) VerifyEmail(c *gin.Context) {
+	var query dto.VerifyEmailQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := eh.verificationService.Verify(query.Token, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MessageResponse{Message: "Email is verified"})
+}
+
+// ResendVerification sends a new verification link
+// @Summary Resend verification email
+// @Description Send a new verification link to the email of the current user. It can be requested at most once per minute by default.
+// @Tags Auth
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 202 {object} dto.MessageResponse "Email sent"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 409 {object} dto.Problem "Email is already verified"
+// @Failure 429 {object} dto.Problem "Requested too often"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Router /api/v1/auth/verify/resend [post]
+func (eh *EmailVerificationHandler) ResendVerification(c *gin.Context) {
+	user, err := eh.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	err = eh.verificationService.Resend(user, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dto.MessageResponse{Message: "Verification email has been sent"})
+}
+
+func NewEmailVerificationHandler(userService *services.UserService, verificationService *services.EmailVerificationService) *EmailVerificationHandler {
+	return &EmailVerificationHandler{userService: userService, verificationService: verificationService}
+}
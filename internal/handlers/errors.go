@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"shop/internal/apperr"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// abort stops the request with err, which is rendered by the error middleware.
+func abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// idParam parses a positive integer path parameter.
+func idParam(c *gin.Context, name string) (uint, error) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 0)
+	if err != nil || id == 0 {
+		return 0, apperr.BadRequest("invalid " + name + " parameter")
+	}
+
+	return uint(id), nil
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"shop/internal/dto"
+	"shop/internal/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	healthService    *services.HealthService
+	readinessTimeout time.Duration
+}
+
+// Liveness reports that the process is alive
+// @Summary Liveness probe
+// @Description Returns 200 as long as the process is able to handle requests. Dependencies are not checked.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} dto.HealthResponse
+// @Router /healthz [get]
+func (hh *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, dto.HealthResponse{Status: dto.HealthOK})
+}
+
+// Readiness reports whether the application can serve traffic
+// @Summary Readiness probe
+// @Description Checks that the server is not shutting down, the database answers a ping and the schema is at the version the application expects.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} dto.HealthResponse
+// @Failure 503 {object} dto.HealthResponse "Some dependency is unavailable"
+// @Router /readyz [get]
+func (hh *HealthHandler) Readiness(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), hh.readinessTimeout)
+	defer cancel()
+	resp, status := hh.healthService.Readiness(ctx)
+
+	c.JSON(status, resp)
+}
+
+func NewHealthHandler(healthService *services.HealthService, readinessTimeout time.Duration) *HealthHandler {
+	return &HealthHandler{healthService: healthService, readinessTimeout: readinessTimeout}
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+	"shop/internal/jwtkeys"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSHandler struct {
+	keys *jwtkeys.KeySet
+}
+
+// JWKS publishes the public keys access tokens are signed with
+// @Summary JSON Web Key Set
+// @Description Public keys other services can verify access tokens with, matched by the kid header of the token. Keys that are being rotated out stay listed until the tokens they signed have expired. Tokens signed with a shared secret have no public key, so the set is empty then.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} jwtkeys.JWKS
+// @Router /.well-known/jwks.json [get]
+func (jh *JWKSHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jh.keys.JWKS())
+}
+
+func NewJWKSHandler(keys *jwtkeys.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
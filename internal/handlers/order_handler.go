@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"net/http"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrderHandler struct {
+	userService  *services.UserService
+	cartService  *services.CartService
+	orderService *services.OrderService
+}
+
+// Checkout create order from cart
+// @Summary Creates order from cart
+// @Description Turns all cart items into an order with the current product prices and empties the cart
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Success 201 {object} dto.OrderResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden or email is not verified"
+// @Failure 409 {object} dto.Problem "Not enough stock"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/orders/checkout [post]
+func (oh *OrderHandler) Checkout(c *gin.Context) {
+	user, err := oh.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	if err := oh.cartService.ValidateUser(user); err != nil {
+		abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	order, err := oh.orderService.Checkout(user, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.OrderToResp(order))
+}
+
+// GetOrders return user orders
+// @Summary Returns user orders
+// @Description Returns orders of the current user, newest first
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} dto.OrderListResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/orders [get]
+func (oh *OrderHandler) GetOrders(c *gin.Context) {
+	var query dto.PaginationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+	query.Normalize()
+
+	user, err := oh.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	orders, total, err := oh.orderService.GetUserOrders(user, query, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.OrdersToListResp(orders, dto.NewPageMeta(query, total)))
+}
+
+// GetSellerOrders return orders of seller products
+// @Summary Returns orders of seller products
+// @Description Returns orders that contain products of the current seller, newest first, each with only the items of the seller and their total. Available for sellers only. Accepts seller API keys with the orders:read scope
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Param status query string false "Order status" Enums(pending, paid, shipped, delivered, cancelled, refunded)
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} dto.OrderListResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/seller/orders [get]
+func (oh *OrderHandler) GetSellerOrders(c *gin.Context) {
+	var query dto.SellerOrderListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+	query.Normalize()
+
+	user, err := oh.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	orders, total, err := oh.orderService.GetSellerOrders(user, query, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.OrdersToListResp(orders, dto.NewPageMeta(query.PaginationQuery, total)))
+}
+
+// GetOrder return order by id
+// @Summary Returns order by id
+// @Description Returns order of the current user with its items and products. Sellers get orders that contain their products, with only their items and their total. The seller route accepts seller API keys with the orders:read scope
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Param id path uint true "Order ID"
+// @Success 200 {object} dto.OrderResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/orders/{id} [get]
+// @Router /api/v1/seller/orders/{id} [get]
+func (oh *OrderHandler) GetOrder(c *gin.Context) {
+	id, err := idParam(c, "id")
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	user, err := oh.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	order, err := oh.orderService.GetOrderIfOwned(id, user, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.OrderToResp(order))
+}
+
+// UpdateOrderStatus change order status
+// @Summary Changes order status
+// @Description Moves the order to a new status. Available for administrators, and for sellers of all ordered products to mark paid orders as shipped and shipped orders as delivered. Accepts seller API keys with the orders:write scope
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Param id path uint true "Order ID"
+// @Param status body dto.UpdateOrderStatusRequest true "New order status"
+// @Success 200 {object} dto.OrderResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 409 {object} dto.Problem "Invalid status transition"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/orders/{id}/status [patch]
+func (oh *OrderHandler) UpdateOrderStatus(c *gin.Context) {
+	id, err := idParam(c, "id")
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	var statusReq dto.UpdateOrderStatusRequest
+	if err := c.ShouldBindJSON(&statusReq); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+	user, err := oh.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	order, err := oh.orderService.UpdateStatus(id, dto.OrderStatus(statusReq.Status), user, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.OrderToResp(order))
+}
+
+func NewOrderHandler(
+	userService *services.UserService,
+	cartService *services.CartService,
+	orderService *services.OrderService,
+) *OrderHandler {
+	return &OrderHandler{
+		userService:  userService,
+		cartService:  cartService,
+		orderService: orderService,
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PasswordResetHandler struct {
+	passwordResetService *services.PasswordResetService
+}
+
+// ForgotPassword sends a password reset link
+// @Summary Request a password reset
+// @Description Email a password reset link to the given address. The response is the same whether or not the address is registered.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ForgotPasswordRequest true "Account email"
+// @Success 202 {object} dto.MessageResponse "Request accepted"
+// @Failure 400 {object} dto.Problem "Invalid input (validation error)"
+// @Failure 429 {object} dto.Problem "Too many requests"
+// @Router /api/v1/auth/password/forgot [post]
+func (ph *PasswordResetHandler) ForgotPassword(c *gin.Context) {
+	var req dto.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+
+	ph.passwordResetService.RequestReset(req, c.Request.Context())
+	c.JSON(http.StatusAccepted, dto.MessageResponse{
+		Message: "If the email is registered, a password reset link has been sent to it",
+	})
+}
+
+// ResetPassword sets a new password
+// @Summary Reset password
+// @Description Set a new password using the token from a password reset link. The token can be used once, and every session of the user is revoked.
+// @Tags Auth
+// @Accept json
+// @Param request body dto.ResetPasswordRequest true "Reset token and new password"
+// @Success 204 "Password changed"
+// @Failure 400 {object} dto.Problem "Invalid input or invalid/expired token"
+// @Failure 429 {object} dto.Problem "Too many requests"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Router /api/v1/auth/password/reset [post]
+func (ph *PasswordResetHandler) ResetPassword(c *gin.Context) {
+	var req dto.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := ph.passwordResetService.ResetPassword(req, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func NewPasswordResetHandler(passwordResetService *services.PasswordResetService) *PasswordResetHandler {
+	return &PasswordResetHandler{passwordResetService: passwordResetService}
+}
@@ -1,14 +1,12 @@
 package handlers
 
 import (
-	"context"
 	"net/http"
+	"shop/internal/apperr"
 	"shop/internal/dto"
 	"shop/internal/models"
 	"shop/internal/repositories"
 	"shop/internal/services"
-	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,68 +25,99 @@ type ProductHandler struct {
 // @Produce json
 // @Param id path int true "Product ID"
 // @Success 200 {object} dto.ProductResponse
-// @Failure 400 {object} map[string]string "Bad request"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 500 {object} dto.Problem "Internal server error"
 // @Router /api/v1/products/{id} [get]
 func (ph *ProductHandler) GetProduct(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
+	id, err := idParam(c, "id")
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abort(c, err)
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-	product, err := ph.productRepository.GetProduct(uint(id), ctx)
+	ctx := c.Request.Context()
+	product, err := ph.productService.GetProduct(id, ctx)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		abort(c, err)
 		return
 	}
 
-	response := dto.ProductResponse{
-		ID:          product.ID,
-		Name:        product.Name,
-		Description: product.Description,
-		Price:       product.Price,
-		ImageUrl:    product.ImageUrl,
-		CategoryID:  product.CategoryID,
-		UserID:      product.UserID,
-		CreatedAt:   product.CreatedAt,
-	}
+	response := dto.ProductToResp(product)
 
 	c.JSON(http.StatusOK, response)
 }
 
 // GetAllProducts return all products
 // @Summary Returns all products
-// @Description Returns all products
+// @Description Returns a page of products filtered and sorted by the query parameters
 // @Tags Products
 // @Accept json
 // @Produce json
-// @Success 200 {object} []dto.ProductResponse
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Param category_id query int false "Category ID, includes subcategories"
+// @Param seller_id query int false "Seller ID"
+// @Param min_price query number false "Minimal price"
+// @Param max_price query number false "Maximal price"
+// @Param sort query string false "Sort field" Enums(price, created_at, name)
+// @Param direction query string false "Sort direction" Enums(asc, desc)
+// @Success 200 {object} dto.ProductListResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 500 {object} dto.Problem "Internal server error"
 // @Router /api/v1/products [get]
 func (ph *ProductHandler) GetAllProducts(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+	var query dto.ProductListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+	query.Normalize()
+
+	ctx := c.Request.Context()
+	products, total, err := ph.productService.GetProducts(query, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	response := dto.ProductListResponse{
+		PageMeta: dto.NewPageMeta(query.PaginationQuery, total),
+		Items:    dto.ProductsToResp(products),
+	}
+
+	c.JSON(http.StatusOK, response)
+}
 
-	products, err := ph.productRepository.GetAll(ctx)
+// SearchProducts search products by text
+// @Summary Searches products
+// @Description Searches products by name and description, most relevant first. Matched words are wrapped in <em> tags in highlights and the rest of their text is HTML escaped
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Param q query string true "Search query"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} dto.ProductSearchResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Router /api/v1/products/search [get]
+func (ph *ProductHandler) SearchProducts(c *gin.Context) {
+	var query dto.ProductSearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+	query.Normalize()
+
+	ctx := c.Request.Context()
+	hits, total, err := ph.productService.SearchProducts(query, ctx)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve products"})
+		abort(c, err)
 		return
 	}
-	var response []dto.ProductResponse
-	for _, product := range products {
-		response = append(response, dto.ProductResponse{
-			ID:          product.ID,
-			Name:        product.Name,
-			Description: product.Description,
-			Price:       product.Price,
-			ImageUrl:    product.ImageUrl,
-			CategoryID:  product.CategoryID,
-			UserID:      product.UserID,
-			CreatedAt:   product.CreatedAt,
-		})
+	response := dto.ProductSearchResponse{
+		PageMeta: dto.NewPageMeta(query.PaginationQuery, total),
+		Items:    hits,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -101,42 +130,46 @@ func (ph *ProductHandler) GetAllProducts(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "User ID"
-// @Success 200 {object} []dto.ProductResponse
-// @Failure 400 {object} map[string]string "Bad Request"
-// @Failure 404 {object} map[string]string "Not found"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Param category_id query int false "Category ID, includes subcategories"
+// @Param min_price query number false "Minimal price"
+// @Param max_price query number false "Maximal price"
+// @Param sort query string false "Sort field" Enums(price, created_at, name)
+// @Param direction query string false "Sort direction" Enums(asc, desc)
+// @Success 200 {object} dto.ProductListResponse
+// @Failure 400 {object} dto.Problem "Bad Request"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 500 {object} dto.Problem "Internal server error"
 // @Router /api/v1/users/{id}/products [get]
 func (ph *ProductHandler) GetProductsBySeller(c *gin.Context) {
-	sellerID, err := strconv.Atoi(c.Param("id"))
+	sellerID, err := idParam(c, "id")
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abort(c, err)
 		return
 	}
+	var query dto.ProductListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+	query.Normalize()
+	query.SellerID = sellerID
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-	products, err := ph.productRepository.GetAllBySellerID(uint(sellerID), ctx)
+	ctx := c.Request.Context()
+	products, total, err := ph.productService.GetProducts(query, ctx)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve products"})
+		abort(c, err)
 		return
 	}
-	if len(products) == 0 {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Products not found"})
+	if total == 0 {
+		abort(c, apperr.NotFound("products not found"))
 		return
 	}
 
-	var response []dto.ProductResponse
-	for _, product := range products {
-		response = append(response, dto.ProductResponse{
-			ID:          product.ID,
-			Name:        product.Name,
-			Description: product.Description,
-			Price:       product.Price,
-			ImageUrl:    product.ImageUrl,
-			CategoryID:  product.CategoryID,
-			UserID:      product.UserID,
-			CreatedAt:   product.CreatedAt,
-		})
+	response := dto.ProductListResponse{
+		PageMeta: dto.NewPageMeta(query.PaginationQuery, total),
+		Items:    dto.ProductsToResp(products),
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -144,32 +177,32 @@ func (ph *ProductHandler) GetProductsBySeller(c *gin.Context) {
 
 // CreateProduct create product
 // @Summary Creates product
-// @Description Creates product and returns one
+// @Description Creates product and returns one. Accepts seller API keys with the products:write scope
 // @Tags Products
 // @Accept json
 // @Produce json
-// @Param credentials body dto.CreateUpdateProductRequest true "Data for create product"
+// @Param credentials body dto.CreateProductRequest true "Data for create product"
 // @Success 201 {object} dto.ProductResponse
-// @Failure 400 {object} map[string]string "Bad request"
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 403 {object} map[string]string "Forbidden"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden or email is not verified"
+// @Failure 500 {object} dto.Problem "Internal server error"
 // @Security ApiKeyAuth
 // @Router /api/v1/products [post]
 func (ph *ProductHandler) CreateProduct(c *gin.Context) {
-	var createReq dto.CreateUpdateProductRequest
+	var createReq dto.CreateProductRequest
 
 	if err := c.ShouldBindJSON(&createReq); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abort(c, apperr.Validation(err))
 		return
 	}
-	user, status := ph.userService.GetUserFromContext(c)
-	if status == http.StatusUnauthorized {
-		c.AbortWithStatusJSON(status, gin.H{"error": "You are unauthorized"})
+	user, err := ph.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
 		return
 	}
 	if user.Type != string(dto.TypeSeller) {
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You are not allow to create product"})
+		abort(c, apperr.Forbidden("only sellers can create products"))
 		return
 	}
 
@@ -180,18 +213,15 @@ func (ph *ProductHandler) CreateProduct(c *gin.Context) {
 		ImageUrl:    createReq.ImageUrl,
 		CategoryID:  createReq.CategoryID,
 		UserID:      user.ID,
+		Stock:       createReq.Stock,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 	if err := ph.productRepository.CreateProduct(product, ctx); err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to create product"})
-		return
-	}
-	if product == nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to create product"})
+		abort(c, apperr.Internal(err))
 		return
 	}
+	ph.productService.IndexProduct(product, ctx)
 	response := dto.ProductToResp(product)
 
 	c.JSON(http.StatusCreated, response)
@@ -199,18 +229,18 @@ func (ph *ProductHandler) CreateProduct(c *gin.Context) {
 
 // UpdateProduct update existing product
 // @Summary Updates existing product
-// @Description Updates existing product and returns one
+// @Description Updates existing product and returns one. Stock is not changed, use the stock endpoint for it. Accepts seller API keys with the products:write scope
 // @Tags Products
 // @Accept json
 // @Produce json
 // @Param id path uint true "Product ID"
 // @Param credentials body dto.CreateUpdateProductRequest true "Data for update existing product"
 // @Success 200 {object} dto.ProductResponse
-// @Failure 400 {object} map[string]string "Bad request"
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 403 {object} map[string]string "Forbidden"
-// @Failure 404 {object} map[string]string "Not found"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 500 {object} dto.Problem "Internal server error"
 // @Security ApiKeyAuth
 // @Router /api/v1/products/{id} [put]
 func (ph *ProductHandler) UpdateProduct(c *gin.Context) {
@@ -219,17 +249,16 @@ func (ph *ProductHandler) UpdateProduct(c *gin.Context) {
 		return
 	}
 
-	productCtx, productCancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer productCancel()
-	existingProduct, status, err := ph.productService.GetProductIfAuthorized(id, user, productCtx)
+	ctx := c.Request.Context()
+	existingProduct, err := ph.productService.GetProductIfAuthorized(id, user, ctx)
 	if err != nil {
-		c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+		abort(c, err)
 		return
 	}
 
 	updateReq := dto.CreateUpdateProductRequest{}
 	if err := c.ShouldBindJSON(&updateReq); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abort(c, apperr.Validation(err))
 		return
 	}
 	updatedProduct := &models.Product{
@@ -240,15 +269,15 @@ func (ph *ProductHandler) UpdateProduct(c *gin.Context) {
 		ImageUrl:    updateReq.ImageUrl,
 		CategoryID:  updateReq.CategoryID,
 		UserID:      existingProduct.UserID,
+		Stock:       existingProduct.Stock,
 		CreatedAt:   existingProduct.CreatedAt,
 	}
 
-	ctx, updateCancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer updateCancel()
 	if err := ph.productRepository.UpdateProduct(updatedProduct, ctx); err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product"})
+		abort(c, apperr.Internal(err))
 		return
 	}
+	ph.productService.IndexProduct(updatedProduct, ctx)
 	response := dto.ProductToResp(updatedProduct)
 
 	c.JSON(http.StatusOK, response)
@@ -256,56 +285,99 @@ func (ph *ProductHandler) UpdateProduct(c *gin.Context) {
 
 // DeleteProduct delete product
 // @Summary Deletes existing product
-// @Description Deletes existing product
+// @Description Deletes existing product. Products that have been ordered can't be deleted, set their stock to 0 instead. Accepts seller API keys with the products:write scope
 // @Tags Products
 // @Accept json
 // @Produce json
 // @Success 204 {object} nil
 // @Param id path uint true "Product ID"
-// @Failure 400 {object} map[string]string "Bad request"
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 403 {object} map[string]string "Forbidden"
-// @Failure 404 {object} map[string]string "Not found"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 409 {object} dto.Problem "Product has been ordered"
+// @Failure 500 {object} dto.Problem "Internal server error"
 // @Security ApiKeyAuth
 // @Router /api/v1/products/{id} [delete]
+// @Router /api/v1/admin/products/{id} [delete]
 func (ph *ProductHandler) DeleteProduct(c *gin.Context) {
 	id, user := ph.getUserAndID(c)
 	if id == 0 && user == nil {
 		return
 	}
 
-	productCtx, productCancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer productCancel()
-	_, status, err := ph.productService.GetProductIfAuthorized(id, user, productCtx)
+	ctx := c.Request.Context()
+	_, err := ph.productService.GetProductIfDeletable(id, user, ctx)
 	if err != nil {
-		c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+		abort(c, err)
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-	if err := ph.productRepository.DeleteProduct(id, ctx); err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete product"})
+	if err := ph.productService.DeleteProduct(id, ctx); err != nil {
+		abort(c, err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
+// UpdateProductStock update product stock
+// @Summary Updates product stock
+// @Description Sets the quantity of the product available for sale, or adjusts it by a positive or negative quantity. Orders change the stock concurrently, so when setting it pass the stock it was based on as expected_stock: the request then fails with 409 if the stock has changed. Accepts seller API keys with the products:write scope
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Param id path uint true "Product ID"
+// @Param stock body dto.UpdateStockRequest true "Available quantity or adjustment"
+// @Success 200 {object} dto.ProductResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 409 {object} dto.Problem "Stock has changed or would go below zero"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/products/{id}/stock [patch]
+func (ph *ProductHandler) UpdateProductStock(c *gin.Context) {
+	id, user := ph.getUserAndID(c)
+	if id == 0 && user == nil {
+		return
+	}
+
+	var stockReq dto.UpdateStockRequest
+	if err := c.ShouldBindJSON(&stockReq); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	product, err := ph.productService.GetProductIfAuthorized(id, user, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	if err := ph.productService.UpdateStock(product, stockReq, ctx); err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ProductToResp(product))
+}
+
 func (ph *ProductHandler) getUserAndID(c *gin.Context) (uint, *models.User) {
-	id, err := strconv.Atoi(c.Param("id"))
+	id, err := idParam(c, "id")
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abort(c, err)
 		return 0, nil
 	}
-	user, status := ph.userService.GetUserFromContext(c)
-	if status == http.StatusUnauthorized {
-		c.AbortWithStatusJSON(status, gin.H{"error": "You are unauthorized"})
+	user, err := ph.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
 		return 0, nil
 	}
 
-	return uint(id), user
+	return id, user
 }
 
 func NewProductHandler(
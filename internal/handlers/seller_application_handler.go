@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"net/http"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SellerApplicationHandler struct {
+	userService        *services.UserService
+	applicationService *services.SellerApplicationService
+}
+
+// Apply apply to become a seller
+// @Summary Applies to become a seller
+// @Description Creates a seller application of the current customer to be reviewed by an administrator
+// @Tags Seller applications
+// @Accept json
+// @Produce json
+// @Param application body dto.SellerApplicationRequest true "Application data"
+// @Success 201 {object} dto.SellerApplicationResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 409 {object} dto.Problem "Pending application exists"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/seller-applications [post]
+func (sh *SellerApplicationHandler) Apply(c *gin.Context) {
+	var applicationReq dto.SellerApplicationRequest
+	if err := c.ShouldBindJSON(&applicationReq); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+	user, err := sh.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	application, err := sh.applicationService.Apply(user, applicationReq, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.SellerApplicationToResp(application))
+}
+
+// GetMyApplications return user seller applications
+// @Summary Returns user seller applications
+// @Description Returns seller applications of the current user, newest first
+// @Tags Seller applications
+// @Accept json
+// @Produce json
+// @Param status query string false "Application status" Enums(pending, approved, rejected)
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} dto.SellerApplicationListResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/seller-applications [get]
+func (sh *SellerApplicationHandler) GetMyApplications(c *gin.Context) {
+	user, err := sh.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	sh.getApplications(c, user.ID)
+}
+
+// GetApplications return all seller applications
+// @Summary Returns all seller applications
+// @Description Returns seller applications of all users, newest first. Available for administrators only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param status query string false "Application status" Enums(pending, approved, rejected)
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} dto.SellerApplicationListResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/seller-applications [get]
+func (sh *SellerApplicationHandler) GetApplications(c *gin.Context) {
+	sh.getApplications(c, 0)
+}
+
+// ApproveApplication approve seller application
+// @Summary Approves seller application
+// @Description Approves pending seller application and makes the applicant a seller. Available for administrators only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path uint true "Application ID"
+// @Success 200 {object} dto.SellerApplicationResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 409 {object} dto.Problem "Application is already reviewed"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/seller-applications/{id}/approve [post]
+func (sh *SellerApplicationHandler) ApproveApplication(c *gin.Context) {
+	sh.review(c, true)
+}
+
+// RejectApplication reject seller application
+// @Summary Rejects seller application
+// @Description Rejects pending seller application. Available for administrators only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path uint true "Application ID"
+// @Success 200 {object} dto.SellerApplicationResponse
+// @Failure 400 {object} dto.Problem "Bad request"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 403 {object} dto.Problem "Forbidden"
+// @Failure 404 {object} dto.Problem "Not found"
+// @Failure 409 {object} dto.Problem "Application is already reviewed"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/seller-applications/{id}/reject [post]
+func (sh *SellerApplicationHandler) RejectApplication(c *gin.Context) {
+	sh.review(c, false)
+}
+
+func (sh *SellerApplicationHandler) getApplications(c *gin.Context, userID uint) {
+	var query dto.SellerApplicationListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+	query.Normalize()
+
+	ctx := c.Request.Context()
+	applications, total, err := sh.applicationService.GetApplications(userID, query, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SellerApplicationsToListResp(applications, dto.NewPageMeta(query.PaginationQuery, total)))
+}
+
+func (sh *SellerApplicationHandler) review(c *gin.Context, approve bool) {
+	id, err := idParam(c, "id")
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	admin, err := sh.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	application, err := sh.applicationService.Review(id, approve, admin, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SellerApplicationToResp(application))
+}
+
+func NewSellerApplicationHandler(
+	userService *services.UserService,
+	applicationService *services.SellerApplicationService,
+) *SellerApplicationHandler {
+	return &SellerApplicationHandler{
+		userService:        userService,
+		applicationService: applicationService,
+	}
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"net/http"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/models"
+	"shop/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorHandler struct {
+	userService      *services.UserService
+	twoFactorService *services.TwoFactorService
+}
+
+// Login completes a login with two-factor authentication
+// @Summary Login with two-factor code
+// @Description Exchange the login challenge returned by /auth/login and a code from the authenticator app or a recovery code for a JWT token. Each code can be used once.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.TwoFactorLoginRequest true "Login challenge and code"
+// @Success 200 {object} dto.TokenResponse "Access and refresh tokens"
+// @Failure 400 {object} dto.Problem "Invalid input (validation error)"
+// @Failure 401 {object} dto.Problem "Invalid or expired challenge, or invalid code"
+// @Failure 403 {object} dto.Problem "Account is banned"
+// @Failure 429 {object} dto.Problem "Too many requests, too many failed attempts or account is locked"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Router /api/v1/auth/login/2fa [post]
+func (th *TwoFactorHandler) Login(c *gin.Context) {
+	var req dto.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+
+	tokens, err := th.twoFactorService.CompleteLogin(req, c.ClientIP(), c.Request.Context())
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// EnrollTOTP starts enabling two-factor authentication
+// @Summary Enroll authenticator app
+// @Description Generate a new authenticator app secret for the current user. Two-factor authentication is enabled once the secret is confirmed with a code.
+// @Tags Auth
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.TOTPEnrollmentResponse "Secret and provisioning URI for a QR code"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 409 {object} dto.Problem "Two-factor authentication is already enabled"
+// @Failure 429 {object} dto.Problem "Too many requests"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Router /api/v1/auth/2fa/totp [post]
+func (th *TwoFactorHandler) EnrollTOTP(c *gin.Context) {
+	user, err := th.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	enrollment, err := th.twoFactorService.Enroll(user, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTOTP enables two-factor authentication
+// @Summary Confirm authenticator app
+// @Description Enable two-factor authentication with a code from the enrolled authenticator app. Returns recovery codes, which are shown only once.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.TwoFactorCodeRequest true "Authenticator app code"
+// @Success 200 {object} dto.RecoveryCodesResponse "Recovery codes"
+// @Failure 400 {object} dto.Problem "Invalid input or enrollment not started"
+// @Failure 401 {object} dto.Problem "Unauthorized or invalid code"
+// @Failure 409 {object} dto.Problem "Two-factor authentication is already enabled"
+// @Failure 429 {object} dto.Problem "Too many requests"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Router /api/v1/auth/2fa/totp/confirm [post]
+func (th *TwoFactorHandler) ConfirmTOTP(c *gin.Context) {
+	user, req, ok := th.bindCode(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	codes, err := th.twoFactorService.Confirm(user, req, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, codes)
+}
+
+// DisableTOTP disables two-factor authentication
+// @Summary Disable two-factor authentication
+// @Description Disable two-factor authentication of the current user and delete the recovery codes. Requires a code from the authenticator app or a recovery code. Wrong codes count as failed logins and lead to the same lockout.
+// @Tags Auth
+// @Accept json
+// @Security ApiKeyAuth
+// @Param request body dto.TwoFactorCodeRequest true "Authenticator app or recovery code"
+// @Success 204 "Two-factor authentication disabled"
+// @Failure 400 {object} dto.Problem "Invalid input or two-factor authentication not enabled"
+// @Failure 401 {object} dto.Problem "Unauthorized or invalid code"
+// @Failure 429 {object} dto.Problem "Too many requests, too many failed attempts or account is locked"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Router /api/v1/auth/2fa/totp/disable [post]
+func (th *TwoFactorHandler) DisableTOTP(c *gin.Context) {
+	user, req, ok := th.bindCode(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := th.twoFactorService.Disable(user, req, c.ClientIP(), ctx); err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes replaces recovery codes
+// @Summary Regenerate recovery codes
+// @Description Replace every recovery code of the current user with new ones. Requires a code from the authenticator app or a recovery code. Wrong codes count as failed logins and lead to the same lockout.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.TwoFactorCodeRequest true "Authenticator app or recovery code"
+// @Success 200 {object} dto.RecoveryCodesResponse "Recovery codes"
+// @Failure 400 {object} dto.Problem "Invalid input or two-factor authentication not enabled"
+// @Failure 401 {object} dto.Problem "Unauthorized or invalid code"
+// @Failure 429 {object} dto.Problem "Too many requests, too many failed attempts or account is locked"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Router /api/v1/auth/2fa/recovery-codes [post]
+func (th *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	user, req, ok := th.bindCode(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	codes, err := th.twoFactorService.RegenerateRecoveryCodes(user, req, c.ClientIP(), ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, codes)
+}
+
+// bindCode returns the current user and the code of the request. It aborts the request
+// and returns false if either is missing.
+func (th *TwoFactorHandler) bindCode(c *gin.Context) (*models.User, dto.TwoFactorCodeRequest, bool) {
+	var req dto.TwoFactorCodeRequest
+	user, err := th.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
+		return nil, req, false
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abort(c, apperr.Validation(err))
+		return nil, req, false
+	}
+
+	return user, req, true
+}
+
+func NewTwoFactorHandler(userService *services.UserService, twoFactorService *services.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{userService: userService, twoFactorService: twoFactorService}
+}
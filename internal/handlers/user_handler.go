@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"shop/internal/apperr"
 	"shop/internal/dto"
 	"shop/internal/services"
 
@@ -9,7 +10,8 @@ import (
 )
 
 type UserHandler struct {
-	userService *services.UserService
+	userService  *services.UserService
+	tokenService *services.TokenService
 }
 
 // Register user
@@ -20,55 +22,157 @@ type UserHandler struct {
 // @Produce json
 // @Param user body dto.RegisterRequest true "User registration data"
 // @Success 201 {object} dto.RegisterResponse "Created user"
-// @Failure 400 {object} map[string]string "Invalid input (validation error)"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 400 {object} dto.Problem "Invalid input (validation error)"
+// @Failure 429 {object} dto.Problem "Too many requests"
+// @Failure 500 {object} dto.Problem "Internal server error"
 // @Router /api/v1/auth/register [post]
 func (uh *UserHandler) Register(c *gin.Context) {
 	var register dto.RegisterRequest
 
 	if err := c.ShouldBindJSON(&register); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abort(c, apperr.Validation(err))
 		return
 	}
 
-	regRes, errMsg, status := uh.userService.Register(register)
-	if errMsg != "" {
-		c.AbortWithStatusJSON(status, gin.H{"error": errMsg})
+	regRes, err := uh.userService.Register(register, c.Request.Context())
+	if err != nil {
+		abort(c, err)
 		return
 	}
 
-	c.JSON(status, regRes)
+	c.JSON(http.StatusCreated, regRes)
 }
 
 // Login authenticates a user and returns a JWT token
 // @Summary Login user
-// @Description Authenticate user by username and password and return a JWT token
+// @Description Authenticate user by username and password and return a JWT token. If the user has two-factor authentication enabled, a login challenge is returned instead, to be completed at /auth/login/2fa.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param credentials body dto.LoginRequest true "User login credentials"
-// @Success 200 {object} dto.LoginResponse "JWT token"
-// @Failure 400 {object} map[string]string "Invalid input (validation error)"
-// @Failure 401 {object} map[string]string "Invalid username or password"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Success 200 {object} dto.LoginResponse "Access and refresh tokens, or a login challenge"
+// @Failure 400 {object} dto.Problem "Invalid input (validation error)"
+// @Failure 401 {object} dto.Problem "Invalid username or password"
+// @Failure 403 {object} dto.Problem "Account is banned"
+// @Failure 429 {object} dto.Problem "Too many requests, too many failed attempts or account is locked"
+// @Failure 500 {object} dto.Problem "Internal server error"
 // @Router /api/v1/auth/login [post]
 func (uh *UserHandler) Login(c *gin.Context) {
 	var login dto.LoginRequest
 
 	if err := c.ShouldBindJSON(&login); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abort(c, apperr.Validation(err))
 		return
 	}
 
-	logRes, errMsg, status := uh.userService.Login(login)
-	if errMsg != "" {
-		c.AbortWithStatusJSON(status, gin.H{"error": errMsg})
+	logRes, err := uh.userService.Login(login, c.ClientIP(), c.Request.Context())
+	if err != nil {
+		abort(c, err)
 		return
 	}
 
-	c.JSON(status, logRes)
+	c.JSON(http.StatusOK, logRes)
 }
 
-func NewUserHandler(userService *services.UserService) *UserHandler {
-	return &UserHandler{userService: userService}
+// Refresh exchanges a refresh token for a new token pair
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access token and a new refresh token. The presented refresh token is revoked; presenting it again revokes every token of that login session.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} dto.TokenResponse "Access and refresh tokens"
+// @Failure 400 {object} dto.Problem "Invalid input (validation error)"
+// @Failure 401 {object} dto.Problem "Invalid, expired or revoked refresh token"
+// @Failure 403 {object} dto.Problem "Account is banned"
+// @Failure 429 {object} dto.Problem "Too many requests"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Router /api/v1/auth/refresh [post]
+func (uh *UserHandler) Refresh(c *gin.Context) {
+	var req dto.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abort(c, apperr.Validation(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	tokens, err := uh.tokenService.Refresh(req.RefreshToken, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout revokes the current access token and its refresh token
+// @Summary Logout
+// @Description Revoke the access token of the request. If a refresh token is given, it and every token refreshed from the same login are revoked too.
+// @Tags Auth
+// @Accept json
+// @Security ApiKeyAuth
+// @Param request body dto.LogoutRequest false "Refresh token to revoke"
+// @Success 204 "Logged out"
+// @Failure 400 {object} dto.Problem "Invalid input (validation error)"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Router /api/v1/auth/logout [post]
+func (uh *UserHandler) Logout(c *gin.Context) {
+	user, err := uh.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	claims, err := uh.userService.GetTokenClaimsFromContext(c)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	var req dto.LogoutRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abort(c, apperr.Validation(err))
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	err = uh.tokenService.Logout(user, claims, req.RefreshToken, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// LogoutAll revokes every token of the user
+// @Summary Logout from all devices
+// @Description Revoke every access and refresh token of the current user.
+// @Tags Auth
+// @Security ApiKeyAuth
+// @Success 204 "Logged out"
+// @Failure 401 {object} dto.Problem "Unauthorized"
+// @Failure 500 {object} dto.Problem "Internal server error"
+// @Router /api/v1/auth/logout-all [post]
+func (uh *UserHandler) LogoutAll(c *gin.Context) {
+	user, err := uh.userService.GetUserFromContext(c)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	err = uh.tokenService.LogoutAll(user, ctx)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func NewUserHandler(userService *services.UserService, tokenService *services.TokenService) *UserHandler {
+	return &UserHandler{userService: userService, tokenService: tokenService}
 }
@@ -0,0 +1,129 @@
+// Package jwtkeys holds the keys access tokens are signed and verified with.
+//
+// Tokens are signed either with a shared HMAC secret (HS256) or with RSA (RS256) or
+// Ed25519 (EdDSA) private keys loaded from a directory. A key set has one signing key
+// and any number of keys that only verify tokens. Tokens name the key that signed
+// them in their kid header, and public keys are published as a JWK set so other
+// services can verify tokens too.
+//
+// To rotate keys, generate a new key into the directory and make it the signing key
+// by its kid. The previous key keeps verifying the tokens it signed; replace its
+// private key with the public one or delete it once the longest-lived of them has
+// expired.
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is a key identified by a kid. Keys that only verify tokens have no signing key.
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	signingKey      any
+	verificationKey any
+}
+
+// KeySet is the signing key and the keys tokens are verified with, the signing key
+// among them.
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// NewHMAC returns a key set that signs and verifies tokens with the secret. The key
+// has an empty kid, so tokens signed with it carry no kid header.
+func NewHMAC(secret []byte) *KeySet {
+	key := &Key{Method: jwt.SigningMethodHS256, signingKey: secret, verificationKey: secret}
+	return &KeySet{signing: key, keys: map[string]*Key{key.ID: key}}
+}
+
+// New returns a key set of asymmetric keys that signs tokens with the key of the
+// signing kid.
+func New(keys []*Key, signingID string) (*KeySet, error) {
+	set := &KeySet{keys: make(map[string]*Key, len(keys))}
+	for _, key := range keys {
+		if _, exists := set.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		set.keys[key.ID] = key
+	}
+
+	signing, ok := set.keys[signingID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q not found", signingID)
+	}
+	if signing.signingKey == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", signingID)
+	}
+	set.signing = signing
+
+	return set, nil
+}
+
+// NewKey returns a key from an RSA or Ed25519 private or public key.
+func NewKey(id string, key any) (*Key, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, signingKey: k, verificationKey: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, verificationKey: k}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, signingKey: k, verificationKey: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, verificationKey: k}, nil
+	default:
+		return nil, fmt.Errorf("key %q: unsupported key type %T, only RSA and Ed25519 keys are supported", id, key)
+	}
+}
+
+// Sign signs the token with the signing key and sets its kid header.
+func (s *KeySet) Sign(token *jwt.Token) (string, error) {
+	token.Method = s.signing.Method
+	token.Header["alg"] = s.signing.Method.Alg()
+	if s.signing.ID != "" {
+		token.Header["kid"] = s.signing.ID
+	}
+
+	return token.SignedString(s.signing.signingKey)
+}
+
+// Keyfunc returns the key a token is verified with, looked up by its kid header.
+func (s *KeySet) Keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("token algorithm doesn't match its key")
+	}
+
+	return key.verificationKey, nil
+}
+
+// Algorithms lists the signing algorithms of the keys, to be passed to
+// jwt.WithValidMethods.
+func (s *KeySet) Algorithms() []string {
+	var algs []string
+	seen := make(map[string]bool)
+	for _, key := range s.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+
+	return algs
+}
+
+// SigningKeyID returns the kid of the key new tokens are signed with.
+func (s *KeySet) SigningKeyID() string {
+	return s.signing.ID
+}
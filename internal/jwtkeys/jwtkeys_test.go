@@ -0,0 +1,200 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newKey(t *testing.T, id string, key any) *Key {
+	t.Helper()
+	k, err := NewKey(id, key)
+	if err != nil {
+		t.Fatalf("NewKey %q: %v", id, err)
+	}
+	return k
+}
+
+func newEd25519(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate Ed25519 key: %v", err)
+	}
+	return key
+}
+
+// sign signs a token with the method and key, whatever keys a set holds, so tokens
+// can be forged with any kid.
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "1"})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return signed
+}
+
+func TestKeyfunc(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, minRSABits)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	edKey := newEd25519(t)
+	retiredKey := newEd25519(t)
+
+	set, err := New([]*Key{
+		newKey(t, "rsa", rsaKey),
+		newKey(t, "ed", edKey),
+		newKey(t, "retired", retiredKey.Public()),
+	}, "ed")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	signed, err := set.Sign(jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"sub": "1"}))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		wantOK bool
+	}{
+		{"signed by the set", signed, true},
+		{"verification key", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey), true},
+		{"lone public key", sign(t, jwt.SigningMethodEdDSA, "retired", retiredKey), true},
+		{"unknown kid", sign(t, jwt.SigningMethodEdDSA, "unknown", edKey), false},
+		{"missing kid", sign(t, jwt.SigningMethodEdDSA, "", edKey), false},
+		{"RS256 token with EdDSA kid", sign(t, jwt.SigningMethodRS256, "ed", rsaKey), false},
+		{"HS256 token with EdDSA kid", sign(t, jwt.SigningMethodHS256, "ed", []byte(edKey.Public().(ed25519.PublicKey))), false},
+		{"signed by another key", sign(t, jwt.SigningMethodEdDSA, "ed", newEd25519(t)), false},
+	}
+
+	for _, tt := range tests {
+		_, err := jwt.Parse(tt.token, set.Keyfunc, jwt.WithValidMethods(set.Algorithms()))
+		if ok := err == nil; ok != tt.wantOK {
+			t.Errorf("%s: verified = %v, want %v (error %v)", tt.name, ok, tt.wantOK, err)
+		}
+	}
+}
+
+func TestNewRefusesPublicSigningKey(t *testing.T) {
+	key := newEd25519(t)
+	_, err := New([]*Key{newKey(t, "public", key.Public())}, "public")
+	if err == nil || !strings.Contains(err.Error(), "no private key") {
+		t.Errorf("New with a public signing key: error %v, want no private key", err)
+	}
+}
+
+func writePEM(t *testing.T, path string, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func writePrivate(t *testing.T, path string, key any) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal private key: %v", err)
+	}
+	writePEM(t, path, "PRIVATE KEY", der)
+}
+
+func writePublic(t *testing.T, path string, key any) {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("marshal public key: %v", err)
+	}
+	writePEM(t, path, "PUBLIC KEY", der)
+}
+
+func TestLoadDir(t *testing.T) {
+	signingKey := newEd25519(t)
+	otherKey := newEd25519(t)
+
+	tests := []struct {
+		name      string
+		write     func(t *testing.T, dir string)
+		signingID string
+		wantErr   string
+	}{
+		{
+			name: "private key",
+			write: func(t *testing.T, dir string) {
+				writePrivate(t, filepath.Join(dir, "current.pem"), signingKey)
+			},
+		},
+		{
+			name: "private key next to its public key",
+			write: func(t *testing.T, dir string) {
+				writePrivate(t, filepath.Join(dir, "current.pem"), signingKey)
+				writePublic(t, filepath.Join(dir, "current.pub.pem"), signingKey.Public())
+			},
+		},
+		{
+			name: "private key next to another public key",
+			write: func(t *testing.T, dir string) {
+				writePrivate(t, filepath.Join(dir, "current.pem"), signingKey)
+				writePublic(t, filepath.Join(dir, "current.pub.pem"), otherKey.Public())
+			},
+			wantErr: "does not match",
+		},
+		{
+			name: "public key only",
+			write: func(t *testing.T, dir string) {
+				writePublic(t, filepath.Join(dir, "current.pub.pem"), signingKey.Public())
+			},
+			wantErr: "0 private keys",
+		},
+		{
+			name: "public key as signing key",
+			write: func(t *testing.T, dir string) {
+				writePrivate(t, filepath.Join(dir, "current.pem"), signingKey)
+				writePublic(t, filepath.Join(dir, "old.pub.pem"), otherKey.Public())
+			},
+			signingID: "old",
+			wantErr:   "no private key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tt.write(t, dir)
+
+			set, err := LoadDir(dir, tt.signingID)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadDir: error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadDir: %v", err)
+			}
+			if set.SigningKeyID() != "current" {
+				t.Errorf("SigningKeyID = %q, want current", set.SigningKeyID())
+			}
+			if _, err := set.Sign(jwt.New(jwt.SigningMethodNone)); err != nil {
+				t.Errorf("Sign: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// minRSABits is the smallest RSA key accepted.
+const minRSABits = 2048
+
+// LoadDir loads the keys from the PEM files in dir. The kid of a key is its file name
+// without the .pem extension, or without .pub.pem for public keys, which only verify
+// tokens. A private key may sit next to its own public key under the same kid, the
+// private one is used then. If signingID is empty, the only private key in dir signs
+// tokens.
+func LoadDir(dir string, signingID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*Key, len(paths))
+	for _, path := range paths {
+		id := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".pem"), ".pub")
+		key, err := loadKey(id, path)
+		if err != nil {
+			return nil, err
+		}
+		if other, exists := byID[id]; exists {
+			if key, err = pair(key, other); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		byID[id] = key
+	}
+
+	keys := make([]*Key, 0, len(byID))
+	var privateIDs []string
+	for id, key := range byID {
+		keys = append(keys, key)
+		if key.signingKey != nil {
+			privateIDs = append(privateIDs, id)
+		}
+	}
+
+	if signingID == "" {
+		if len(privateIDs) != 1 {
+			return nil, fmt.Errorf("%s has %d private keys, the signing key id must be given unless there is exactly one", dir, len(privateIDs))
+		}
+		signingID = privateIDs[0]
+	}
+
+	return New(keys, signingID)
+}
+
+// pair picks the private key of two keys with the same kid, which must be a private key
+// and its own public key.
+func pair(a, b *Key) (*Key, error) {
+	private, public := a, b
+	if private.signingKey == nil {
+		private, public = b, a
+	}
+	if private.signingKey == nil || public.signingKey != nil {
+		return nil, fmt.Errorf("duplicate key id %q", a.ID)
+	}
+
+	verificationKey, ok := private.verificationKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !verificationKey.Equal(public.verificationKey) {
+		return nil, fmt.Errorf("public key %q does not match the private key", a.ID)
+	}
+
+	return private, nil
+}
+
+func loadKey(id string, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("%s: RSA keys must be at least %d bits", path, minRSABits)
+		}
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("%s: RSA keys must be at least %d bits", path, minRSABits)
+		}
+	}
+
+	return NewKey(id, parsed)
+}
+
+// Generate writes a new private key for the algorithm, RS256 or EdDSA, into dir and
+// returns its kid. The kid starts with the current date, so keys sort by age.
+func Generate(dir string, alg string) (string, error) {
+	var key any
+	var err error
+	switch alg {
+	case "RS256":
+		key, err = rsa.GenerateKey(rand.Reader, 3072)
+	case "EdDSA":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return "", fmt.Errorf("unsupported algorithm %q, use RS256 or EdDSA", alg)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	id := time.Now().UTC().Format("20060102") + "-" + hex.EncodeToString(suffix)
+
+	file, err := os.OpenFile(filepath.Join(dir, id+".pem"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", err
+	}
+	err = pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	return id, errors.Join(err, file.Close())
+}
@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileMailer appends messages to a file instead of delivering them, so that links
+// sent by email can be followed during local development.
+type FileMailer struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (fm *FileMailer) Send(message Message, ctx context.Context) error {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	file, err := os.OpenFile(fm.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(file, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n----\n\n",
+		time.Now().Format(time.RFC1123Z), message.To, message.Subject, message.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func NewFileMailer(path string) *FileMailer {
+	return &FileMailer{path: path}
+}
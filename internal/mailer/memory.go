@@ -0,0 +1,35 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryMailer keeps sent messages in memory instead of delivering them.
+// It is meant for tests.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (mm *MemoryMailer) Send(message Message, ctx context.Context) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	mm.messages = append(mm.messages, message)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (mm *MemoryMailer) Messages() []Message {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	messages := make([]Message, len(mm.messages))
+	copy(messages, mm.messages)
+	return messages
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
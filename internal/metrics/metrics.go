@@ -0,0 +1,131 @@
+// Package metrics collects the Prometheus metrics of the application: HTTP traffic,
+// database queries and connection pool, and business events.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "shop"
+
+// Cart update actions used as the action label of the carts updated counter.
+const (
+	CartItemAdded   = "add"
+	CartItemUpdated = "update"
+	CartItemRemoved = "remove"
+	CartCleared     = "clear"
+)
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	dbQueryDuration *prometheus.HistogramVec
+	dbQueryErrors   *prometheus.CounterVec
+
+	cartsUpdated *prometheus.CounterVec
+	ordersPlaced prometheus.Counter
+	revenue      prometheus.Counter
+}
+
+// New creates the metrics in their own registry, along with the Go runtime, process
+// and connection pool collectors. The pool statistics are read from sqlDB on scrape.
+func New(sqlDB *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by route template and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time spent handling HTTP requests, by route template.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+
+		dbQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Time spent running database queries, by operation and table.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "table"}),
+		dbQueryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_query_errors_total",
+			Help:      "Database queries that failed, by operation and table.",
+		}, []string{"operation", "table"}),
+
+		cartsUpdated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "carts_updated_total",
+			Help:      "Changes made to carts, by action.",
+		}, []string{"action"}),
+		ordersPlaced: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "orders_placed_total",
+			Help:      "Orders placed at checkout.",
+		}),
+		revenue: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "revenue_total",
+			Help:      "Sum of the total prices of placed orders.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.dbQueryDuration,
+		m.dbQueryErrors,
+		m.cartsUpdated,
+		m.ordersPlaced,
+		m.revenue,
+	)
+	if sqlDB != nil {
+		m.registry.MustRegister(collectors.NewDBStatsCollector(sqlDB, "shop"))
+	}
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a handled HTTP request. route is the route template, such as
+// /api/v1/products/:id, so that the number of label values stays bounded.
+func (m *Metrics) ObserveRequest(method, route, status string, duration time.Duration) {
+	m.httpRequests.WithLabelValues(method, route, status).Inc()
+	m.httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// ObserveQuery records a database query.
+func (m *Metrics) ObserveQuery(operation, table string, duration time.Duration, failed bool) {
+	m.dbQueryDuration.WithLabelValues(operation, table).Observe(duration.Seconds())
+	if failed {
+		m.dbQueryErrors.WithLabelValues(operation, table).Inc()
+	}
+}
+
+func (m *Metrics) CartUpdated(action string) {
+	m.cartsUpdated.WithLabelValues(action).Inc()
+}
+
+func (m *Metrics) OrderPlaced(totalPrice float64) {
+	m.ordersPlaced.Inc()
+	m.revenue.Add(totalPrice)
+}
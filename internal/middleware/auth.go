@@ -1,61 +1,75 @@
 package middleware
 
 import (
-	"context"
-	"net/http"
+	"log/slog"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/metrics"
+	"shop/internal/ratelimit"
 	"shop/internal/repositories"
+	"shop/internal/services"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 type Middleware struct {
-	jwtSecret      string
+	tokenService   *services.TokenService
+	apiKeyService  *services.APIKeyService
 	userRepository repositories.UserRepository
+	logger         *slog.Logger
+	metrics        *metrics.Metrics
+	rateLimitStore ratelimit.Store
 }
 
-func GetMiddleware(jwtSecret string, userRepository repositories.UserRepository) *Middleware {
-	return &Middleware{jwtSecret: jwtSecret, userRepository: userRepository}
+func GetMiddleware(
+	tokenService *services.TokenService,
+	apiKeyService *services.APIKeyService,
+	userRepository repositories.UserRepository,
+	logger *slog.Logger,
+	metrics *metrics.Metrics,
+	rateLimitStore ratelimit.Store) *Middleware {
+
+	return &Middleware{
+		tokenService:   tokenService,
+		apiKeyService:  apiKeyService,
+		userRepository: userRepository,
+		logger:         logger,
+		metrics:        metrics,
+		rateLimitStore: rateLimitStore,
+	}
 }
 
 func (m *Middleware) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+		tokenString, err := bearerToken(c)
+		if err != nil {
+			abort(c, err)
 			return
 		}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
+		m.authenticateToken(c, tokenString)
+	}
+}
 
-			return []byte(m.jwtSecret), nil
-		})
-		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+// APIKeyAuth authenticates like AuthMiddleware, but also accepts seller API keys with
+// the scope in place of the access token. Routes guarded by it should not depend on
+// the claims of an access token.
+func (m *Middleware) APIKeyAuth(scope dto.APIKeyScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString, err := bearerToken(c)
+		if err != nil {
+			abort(c, err)
 			return
 		}
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		if !strings.HasPrefix(tokenString, services.APIKeyPrefix) {
+			m.authenticateToken(c, tokenString)
 			return
 		}
-		userIdJson, ok := claims["userId"].(float64)
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token"})
-		}
-		userId := uint(userIdJson)
-		ctx, closeCtx := context.WithTimeout(context.Background(), 3*time.Second)
-		defer closeCtx()
-		user, err := m.userRepository.FindByID(userId, ctx)
+
+		user, err := m.apiKeyService.Authenticate(tokenString, scope, c.Request.Context())
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+			abort(c, err)
 			return
 		}
 
@@ -63,3 +77,44 @@ func (m *Middleware) AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+func (m *Middleware) authenticateToken(c *gin.Context, tokenString string) {
+	claims, err := m.tokenService.ParseAccessToken(tokenString)
+	if err != nil {
+		abort(c, apperr.Unauthorized("invalid token").WithCode(apperr.CodeInvalidToken))
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := m.userRepository.FindByID(claims.UserID, ctx)
+	if err != nil {
+		abort(c, apperr.Unauthorized("unauthorized access"))
+		return
+	}
+	revoked, err := m.tokenService.IsRevoked(claims, user, ctx)
+	if err != nil {
+		abort(c, apperr.Internal(err))
+		return
+	}
+	if revoked {
+		abort(c, apperr.Unauthorized("token is revoked").WithCode(apperr.CodeInvalidToken))
+		return
+	}
+	if user.BannedAt != nil {
+		abort(c, apperr.Forbidden("account is banned").WithCode(apperr.CodeAccountBanned))
+		return
+	}
+
+	c.Set("user", user)
+	c.Set("tokenClaims", claims)
+	c.Next()
+}
+
+func bearerToken(c *gin.Context) (string, error) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		return "", apperr.Unauthorized("authorization header is required")
+	}
+
+	return strings.TrimPrefix(authHeader, "Bearer "), nil
+}
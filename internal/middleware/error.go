@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorHandler renders the last error added to the context with c.Error as an
+// application/problem+json response. Handlers and middlewares only need to add the
+// error and abort. It must be registered before any of them.
+func (m *Middleware) ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := apperr.From(c.Errors.Last().Err)
+		if err.Status >= http.StatusInternalServerError {
+			m.logger.ErrorContext(c.Request.Context(), "Request failed", slog.Any("error", err))
+		} else if err.Unwrap() != nil {
+			m.logger.InfoContext(c.Request.Context(), "Request rejected", slog.Any("error", err))
+		}
+		writeProblem(c, err)
+	}
+}
+
+// Recovery turns a panic in a handler into an internal_error problem. It replaces the
+// recovery middleware of gin, which writes plain text to stderr.
+func (m *Middleware) Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, recovered any) {
+		m.logger.ErrorContext(c.Request.Context(), "Handler panicked", slog.Any("panic", recovered), slog.String("stack", string(debug.Stack())))
+		writeProblem(c, apperr.Internal(fmt.Errorf("panic: %v", recovered)))
+		c.Abort()
+	})
+}
+
+// NoRoute reports unknown routes as a not_found problem.
+func (m *Middleware) NoRoute() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		abort(c, apperr.NotFound("route not found"))
+	}
+}
+
+func writeProblem(c *gin.Context, err *apperr.Error) {
+	c.Header("Content-Type", "application/problem+json")
+	if err.RetryAfter > 0 {
+		c.Header("Retry-After", seconds(err.RetryAfter))
+	}
+	c.JSON(err.Status, dto.ErrorToProblem(err, c.Request.URL.Path, tracing.TraceID(c.Request.Context())))
+}
+
+// abort stops the request with err, which is rendered by ErrorHandler.
+func abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"shop/internal/apperr"
+	"shop/internal/models"
+	"shop/internal/ratelimit"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitKey identifies the client a request is counted against.
+type RateLimitKey func(c *gin.Context) string
+
+// ByIP counts requests per client IP.
+func ByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// ByUser counts requests per authenticated user, and per client IP before
+// authentication. Used after AuthMiddleware it always counts per user.
+func ByUser(c *gin.Context) string {
+	cUser, _ := c.Get("user")
+	if user, ok := cUser.(*models.User); ok {
+		return "user:" + strconv.FormatUint(uint64(user.ID), 10)
+	}
+
+	return ByIP(c)
+}
+
+// RateLimit refuses requests over limit with 429. Every response carries the
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers, refused ones also
+// Retry-After. Limits with different names are counted separately, so a route can be
+// under several of them. If the store fails the request is let through.
+func (m *Middleware) RateLimit(name string, limit ratelimit.Limit, key RateLimitKey) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		result, err := m.rateLimitStore.Take(name+":"+key(c), limit, ctx)
+		if err != nil {
+			m.logger.ErrorContext(ctx, "Rate limit store failed", slog.String("limit", name), slog.Any("error", err))
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", seconds(result.ResetAfter))
+		if !result.Allowed {
+			c.Header("Retry-After", seconds(result.RetryAfter))
+			abort(c, apperr.TooManyRequests("too many requests, try again later"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// seconds formats d as whole seconds, rounded up so clients never retry too early.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shop/internal/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubStore answers every Take with result.
+type stubStore struct {
+	result ratelimit.Result
+}
+
+func (s *stubStore) Take(key string, limit ratelimit.Limit, ctx context.Context) (ratelimit.Result, error) {
+	return s.result, nil
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		result     ratelimit.Result
+		wantStatus int
+		wantHeader map[string]string
+	}{
+		{
+			name:       "allowed",
+			result:     ratelimit.Result{Allowed: true, Limit: 10, Remaining: 4, ResetAfter: 30 * time.Second},
+			wantStatus: http.StatusOK,
+			wantHeader: map[string]string{
+				"RateLimit-Limit":     "10",
+				"RateLimit-Remaining": "4",
+				"RateLimit-Reset":     "30",
+				"Retry-After":         "",
+			},
+		},
+		{
+			name:       "refused",
+			result:     ratelimit.Result{Limit: 10, ResetAfter: 59 * time.Second, RetryAfter: 6 * time.Second},
+			wantStatus: http.StatusTooManyRequests,
+			wantHeader: map[string]string{
+				"RateLimit-Limit":     "10",
+				"RateLimit-Remaining": "0",
+				"RateLimit-Reset":     "59",
+				"Retry-After":         "6",
+			},
+		},
+		{
+			name:       "fractions are rounded up",
+			result:     ratelimit.Result{Limit: 10, ResetAfter: 1500 * time.Millisecond, RetryAfter: 100 * time.Millisecond},
+			wantStatus: http.StatusTooManyRequests,
+			wantHeader: map[string]string{
+				"RateLimit-Reset": "2",
+				"Retry-After":     "1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Middleware{logger: slog.New(slog.DiscardHandler), rateLimitStore: &stubStore{result: tt.result}}
+			router := gin.New()
+			router.Use(m.ErrorHandler())
+			router.GET("/", m.RateLimit("test", ratelimit.Limit{Requests: 10, Period: time.Minute}, ByIP), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			for header, want := range tt.wantHeader {
+				if got := rec.Header().Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+}
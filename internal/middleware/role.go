@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/models"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole lets the request through only if the authenticated user has one of the
+// given types. It must be used after AuthMiddleware.
+func (m *Middleware) RequireRole(types ...dto.UserType) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cUser, exist := c.Get("user")
+		user, ok := cUser.(*models.User)
+		if !exist || !ok {
+			abort(c, apperr.Unauthorized("unauthorized access"))
+			return
+		}
+		if !slices.Contains(types, dto.UserType(user.Type)) {
+			abort(c, apperr.Forbidden("you don't have permissions"))
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const requestContextKey = "requestContext"
+
+// Timeout bounds the request context with the given deadline. Handlers pass the request
+// context down to repositories, so a query is cancelled when the deadline fires or the
+// client disconnects, and ErrorHandler responds with 504 or 499.
+//
+// A Timeout registered on a route replaces the one of its group instead of nesting in
+// it, so single routes can be given more time than the group default.
+func (m *Middleware) Timeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parent := c.Request.Context()
+		if base, exists := c.Get(requestContextKey); exists {
+			parent = base.(context.Context)
+		} else {
+			c.Set(requestContextKey, parent)
+		}
+
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
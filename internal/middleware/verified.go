@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"shop/internal/apperr"
+	"shop/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireVerifiedEmail lets the request through only if the authenticated user has
+// verified their email. Unverified users can still browse and fill their cart, but
+// actions guarded by it, such as checkout, are refused. It must be used after AuthMiddleware.
+func (m *Middleware) RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cUser, exist := c.Get("user")
+		user, ok := cUser.(*models.User)
+		if !exist || !ok {
+			abort(c, apperr.Unauthorized("unauthorized access"))
+			return
+		}
+		if user.EmailVerifiedAt == nil {
+			abort(c, apperr.Forbidden("email is not verified").WithCode(apperr.CodeEmailNotVerified))
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// APIKey lets a seller's own systems call the API without a password. Only the hash of
+// the key is stored; Prefix is its first characters, kept so the seller can tell keys
+// apart.
+type APIKey struct {
+	ID      uint   `gorm:"primaryKey;AUTO_INCREMENT"`
+	UserID  uint   `gorm:"not null;index"`
+	Name    string `gorm:"size:100;not null"`
+	Prefix  string `gorm:"size:16;not null"`
+	KeyHash string `gorm:"size:64;not null;uniqueIndex"`
+	// Scopes is the space separated list of what the key may be used for.
+	Scopes     string `gorm:"size:255;not null"`
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time `gorm:"not null"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
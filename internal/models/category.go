@@ -1,6 +1,10 @@
 package models
 
 type Category struct {
-	ID   uint   `gorm:"primaryKey;AUTO_INCREMENT"`
-	Name string `gorm:"size:100;not null"`
+	ID       uint   `gorm:"primaryKey;AUTO_INCREMENT"`
+	Name     string `gorm:"size:100;not null"`
+	ParentID *uint  `gorm:"default:null"`
+
+	Parent   *Category  `gorm:"foreignKey:ParentID"`
+	Children []Category `gorm:"foreignKey:ParentID"`
 }
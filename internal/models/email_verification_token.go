@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+type EmailVerificationToken struct {
+	ID        uint      `gorm:"primaryKey;AUTO_INCREMENT"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time `gorm:"not null"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
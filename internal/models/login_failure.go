@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginFailure is a failed login attempt. Failures are recorded by email whether or not
+// an account with the email exists.
+type LoginFailure struct {
+	ID        uint      `gorm:"primaryKey;AUTO_INCREMENT"`
+	Email     string    `gorm:"size:100;not null;index"`
+	IP        string    `gorm:"size:45;not null;index"`
+	CreatedAt time.Time `gorm:"not null;index"`
+}
+
+// LoginLockout blocks logins with the email until LockedUntil.
+type LoginLockout struct {
+	Email       string    `gorm:"primaryKey;size:100"`
+	LockedUntil time.Time `gorm:"not null"`
+	CreatedAt   time.Time `gorm:"not null"`
+}
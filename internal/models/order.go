@@ -12,5 +12,7 @@ type Order struct {
 	CreatedAt  time.Time `gorm:"not null"`
 	UpdatedAt  time.Time `gorm:"not null"`
 
-	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	User          User                `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Items         []OrderItem         `gorm:"foreignKey:OrderID"`
+	StatusHistory []OrderStatusChange `gorm:"foreignKey:OrderID"`
 }
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+type OrderStatusChange struct {
+	ID         uint      `gorm:"primaryKey;AUTO_INCREMENT"`
+	OrderID    uint      `gorm:"not null"`
+	FromStatus string    `gorm:"size:100"`
+	ToStatus   string    `gorm:"size:100;not null"`
+	ActorID    *uint     `gorm:"default:null"`
+	CreatedAt  time.Time `gorm:"not null"`
+
+	Order Order `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE"`
+	Actor *User `gorm:"foreignKey:ActorID;constraint:OnDelete:SET NULL"`
+}
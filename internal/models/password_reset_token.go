@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+type PasswordResetToken struct {
+	ID        uint      `gorm:"primaryKey;AUTO_INCREMENT"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"not null"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
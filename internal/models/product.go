@@ -12,6 +12,7 @@ type Product struct {
 	ImageUrl    string    `gorm:"size:255"`
 	CategoryID  uint      `gorm:"not null"`
 	UserID      uint      `gorm:"not null"`
+	Stock       int       `gorm:"not null;default:0"`
 	CreatedAt   time.Time `gorm:"not null"`
 
 	Category Category `gorm:"foreignKey:CategoryID"`
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey;AUTO_INCREMENT"`
+	UserID    uint      `gorm:"not null"`
+	FamilyID  string    `gorm:"size:64;not null;index"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	RevokedAt *time.Time
+	CreatedAt time.Time `gorm:"not null"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
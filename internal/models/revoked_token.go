@@ -0,0 +1,10 @@
+package models
+
+import (
+	"time"
+)
+
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey;size:64"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+type SellerApplication struct {
+	ID         uint      `gorm:"primaryKey;AUTO_INCREMENT"`
+	UserID     uint      `gorm:"not null"`
+	Message    string    `gorm:"size:500"`
+	Status     string    `gorm:"size:100;not null"`
+	ReviewerID *uint     `gorm:"default:null"`
+	CreatedAt  time.Time `gorm:"not null"`
+	ReviewedAt *time.Time
+
+	User     User  `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Reviewer *User `gorm:"foreignKey:ReviewerID;constraint:OnDelete:SET NULL"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// TOTPCredential is the authenticator app secret of a user. Two-factor authentication
+// is enabled once the user confirms the secret with a valid code.
+type TOTPCredential struct {
+	UserID      uint   `gorm:"primaryKey"`
+	Secret      string `gorm:"size:64;not null"`
+	ConfirmedAt *time.Time
+	// LastUsedStep is the time step of the last accepted code, so a code can't be
+	// used twice.
+	LastUsedStep int64     `gorm:"not null;default:0"`
+	CreatedAt    time.Time `gorm:"not null"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+// RecoveryCode is a single-use code that replaces the authenticator app code when the
+// app is lost.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey;AUTO_INCREMENT"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"size:64;not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"not null"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+// LoginChallenge is issued instead of tokens when a user with two-factor
+// authentication enabled logs in with the right password.
+type LoginChallenge struct {
+	ID        uint      `gorm:"primaryKey;AUTO_INCREMENT"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex"`
+	Attempts  uint      `gorm:"not null;default:0"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time `gorm:"not null"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
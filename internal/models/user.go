@@ -1,11 +1,23 @@
 package models
 
+import (
+	"time"
+)
+
 type User struct {
-	ID       uint   `gorm:"primaryKey;AUTO_INCREMENT"`
-	Username string `gorm:"size:100;not null"`
-	Email    string `gorm:"uniqueIndex;size:100;not null"`
-	Password string `gorm:"size:255;not null"`
-	Type     string `gorm:"size:100;not null"`
+	ID       uint       `gorm:"primaryKey;AUTO_INCREMENT"`
+	Username string     `gorm:"size:100;not null"`
+	Email    string     `gorm:"uniqueIndex;size:100;not null"`
+	Password string     `gorm:"size:255;not null"`
+	Type     string     `gorm:"size:100;not null"`
+	BannedAt *time.Time `gorm:"default:null"`
+	// EmailVerifiedAt is nil until the user follows the link from the verification email.
+	EmailVerifiedAt *time.Time `gorm:"default:null"`
+	// TokenVersion is embedded into access tokens; bumping it invalidates all of them.
+	TokenVersion uint `gorm:"not null;default:0"`
+	// LockedUntil is read from the active login lockout of the email, if there is one.
+	// Only UserRepository.FindByID and GetAll load it.
+	LockedUntil *time.Time `gorm:"->;-:migration"`
 
 	Cart *Cart `gorm:"constraint:OnDelete:CASCADE;"`
 }
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often full buckets are dropped from the memory store.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	// tat is the theoretical arrival time: the moment the bucket is full again. A
+	// request is allowed while tat is less than a period ahead of now.
+	tat time.Time
+}
+
+// MemoryStore keeps buckets in process memory. A bucket is stored as a single time, as
+// in the generic cell rate algorithm, which behaves exactly like a token bucket. Buckets
+// that are full again are dropped, so memory use stays proportional to the number of
+// active clients.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit, _ context.Context) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tat: now}
+		s.buckets[key] = b
+	}
+
+	interval := limit.interval()
+	tat := b.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(interval)
+
+	result := Result{Limit: limit.Requests}
+	if next.Sub(now) > limit.Period {
+		result.RetryAfter = next.Sub(now) - limit.Period
+		result.ResetAfter = tat.Sub(now)
+		return result, nil
+	}
+
+	b.tat = next
+	result.Allowed = true
+	result.Remaining = int((limit.Period - next.Sub(now)) / interval)
+	result.ResetAfter = next.Sub(now)
+	return result, nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if !b.tat.After(now) {
+			delete(s.buckets, key)
+		}
+	}
+}
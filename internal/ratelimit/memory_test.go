@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock that only moves when advanced.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestStore() (*MemoryStore, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	store.now = clock.Now
+	return store, clock
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	// One request is regained every second.
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+	steps := []struct {
+		name          string
+		advance       time.Duration
+		wantAllowed   bool
+		wantRemaining int
+		wantReset     time.Duration
+		wantRetry     time.Duration
+	}{
+		{"first request of burst", 0, true, 2, time.Second, 0},
+		{"second request of burst", 0, true, 1, 2 * time.Second, 0},
+		{"last request of burst", 0, true, 0, 3 * time.Second, 0},
+		{"burst exhausted", 0, false, 0, 3 * time.Second, time.Second},
+		{"still refused before a request is regained", 500 * time.Millisecond, false, 0, 2500 * time.Millisecond, 500 * time.Millisecond},
+		{"request regained", 500 * time.Millisecond, true, 0, 3 * time.Second, 0},
+		{"refused again", 0, false, 0, 3 * time.Second, time.Second},
+		{"bucket full again", 10 * time.Second, true, 2, time.Second, 0},
+	}
+
+	store, clock := newTestStore()
+	for _, step := range steps {
+		clock.now = clock.now.Add(step.advance)
+		result, err := store.Take("client", limit, context.Background())
+		if err != nil {
+			t.Fatalf("%s: Take: %v", step.name, err)
+		}
+		if result.Allowed != step.wantAllowed {
+			t.Errorf("%s: Allowed = %v, want %v", step.name, result.Allowed, step.wantAllowed)
+		}
+		if result.Limit != limit.Requests {
+			t.Errorf("%s: Limit = %d, want %d", step.name, result.Limit, limit.Requests)
+		}
+		if result.Remaining != step.wantRemaining {
+			t.Errorf("%s: Remaining = %d, want %d", step.name, result.Remaining, step.wantRemaining)
+		}
+		if result.ResetAfter != step.wantReset {
+			t.Errorf("%s: ResetAfter = %s, want %s", step.name, result.ResetAfter, step.wantReset)
+		}
+		if result.RetryAfter != step.wantRetry {
+			t.Errorf("%s: RetryAfter = %s, want %s", step.name, result.RetryAfter, step.wantRetry)
+		}
+	}
+}
+
+func TestMemoryStoreSeparatesKeys(t *testing.T) {
+	limit := Limit{Requests: 1, Period: time.Minute}
+	store, _ := newTestStore()
+
+	if result, _ := store.Take("a", limit, context.Background()); !result.Allowed {
+		t.Fatal("first request of a was refused")
+	}
+	if result, _ := store.Take("a", limit, context.Background()); result.Allowed {
+		t.Error("second request of a was allowed")
+	}
+	if result, _ := store.Take("b", limit, context.Background()); !result.Allowed {
+		t.Error("first request of b was refused")
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	store, clock := newTestStore()
+
+	_, _ = store.Take("short", Limit{Requests: 1, Period: time.Second}, context.Background())
+	_, _ = store.Take("long", Limit{Requests: 1, Period: time.Hour}, context.Background())
+
+	clock.now = clock.now.Add(sweepInterval)
+	_, _ = store.Take("other", Limit{Requests: 1, Period: time.Second}, context.Background())
+
+	if _, ok := store.buckets["short"]; ok {
+		t.Error("full bucket was not swept")
+	}
+	if _, ok := store.buckets["long"]; !ok {
+		t.Error("bucket that is not full yet was swept")
+	}
+}
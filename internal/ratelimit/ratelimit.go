@@ -0,0 +1,63 @@
+// Package ratelimit limits how often a client may call the API using token buckets.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests requests per Period. Unused requests accumulate up to Requests,
+// so a client may spend the whole allowance in a burst.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit parses limits written as "<requests>/<period>", for example "10/1m".
+func ParseLimit(s string) (Limit, error) {
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit %q must look like 10/1m", s)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q must allow a positive number of requests", s)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q must have a positive period", s)
+	}
+
+	return Limit{Requests: n, Period: d}, nil
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// interval is the time it takes to regain a single request.
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Requests)
+}
+
+// Result describes the state of a bucket after a request was counted against it.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// ResetAfter is how long it takes until the whole allowance is available again.
+	ResetAfter time.Duration
+	// RetryAfter is how long a refused client has to wait for the next request.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets. The in-memory store is enough for a single instance, a
+// shared store lets several instances enforce the same limits.
+type Store interface {
+	// Take counts a request against the bucket with the given key.
+	Take(key string, limit Limit, ctx context.Context) (Result, error)
+}
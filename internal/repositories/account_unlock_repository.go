@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"shop/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrUnlockTokenUsed = errors.New("unlock token is already used")
+
+type AccountUnlockRepository interface {
+	Create(token *models.AccountUnlockToken, ctx context.Context) error
+	FindByHash(hash string, ctx context.Context) (*models.AccountUnlockToken, error)
+	Unlock(token *models.AccountUnlockToken, ctx context.Context) error
+}
+
+type accountUnlockRepository struct {
+	db *gorm.DB
+}
+
+// Create stores a new unlock token and deletes the unused tokens previously issued to
+// the same user, so only the latest link works.
+func (a *accountUnlockRepository) Create(token *models.AccountUnlockToken, ctx context.Context) error {
+	return a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Delete(&models.AccountUnlockToken{}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(token).Error
+	})
+}
+
+func (a *accountUnlockRepository) FindByHash(hash string, ctx context.Context) (*models.AccountUnlockToken, error) {
+	var token models.AccountUnlockToken
+	err := a.db.WithContext(ctx).First(&token, "token_hash = ?", hash).Error
+	return &token, err
+}
+
+// Unlock marks the token as used and lifts the login lockout of its user's email. It
+// returns ErrUnlockTokenUsed if the token has been used by a concurrent request.
+func (a *accountUnlockRepository) Unlock(token *models.AccountUnlockToken, ctx context.Context) error {
+	return a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.AccountUnlockToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrUnlockTokenUsed
+		}
+
+		email := tx.Model(&models.User{}).Select("email").Where("id = ?", token.UserID)
+		err := tx.Where("email = (?)", email).
+			Delete(&models.LoginLockout{}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("email = (?)", email).
+			Delete(&models.LoginFailure{}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		token.UsedAt = &now
+		return nil
+	})
+}
+
+func NewAccountUnlockRepository(db *gorm.DB) AccountUnlockRepository {
+	return &accountUnlockRepository{db: db}
+}
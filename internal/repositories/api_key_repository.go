@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"shop/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	Create(key *models.APIKey, ctx context.Context) error
+	FindByHash(hash string, ctx context.Context) (*models.APIKey, error)
+	GetByUserID(userID uint, ctx context.Context) ([]models.APIKey, error)
+	Revoke(id uint, userID uint, ctx context.Context) error
+	TouchLastUsed(key *models.APIKey, before time.Time, ctx context.Context) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func (a *apiKeyRepository) Create(key *models.APIKey, ctx context.Context) error {
+	return a.db.WithContext(ctx).Create(key).Error
+}
+
+func (a *apiKeyRepository) FindByHash(hash string, ctx context.Context) (*models.APIKey, error) {
+	var key models.APIKey
+	err := a.db.WithContext(ctx).First(&key, "key_hash = ?", hash).Error
+	return &key, err
+}
+
+// GetByUserID returns the keys of the user that haven't been revoked, newest first.
+func (a *apiKeyRepository) GetByUserID(userID uint, ctx context.Context) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := a.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC, id DESC").
+		Find(&keys).
+		Error
+	return keys, err
+}
+
+// Revoke revokes the key if it belongs to the user. It returns gorm.ErrRecordNotFound
+// if the user has no such key that is still active.
+func (a *apiKeyRepository) Revoke(id uint, userID uint, ctx context.Context) error {
+	result := a.db.WithContext(ctx).
+		Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// TouchLastUsed sets the last use of the key to now, unless it has been set after
+// before already. Keys used in bursts are written once in a while rather than on every
+// request.
+func (a *apiKeyRepository) TouchLastUsed(key *models.APIKey, before time.Time, ctx context.Context) error {
+	now := time.Now()
+	err := a.db.WithContext(ctx).
+		Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", key.ID, before).
+		Update("last_used_at", now).
+		Error
+	if err != nil {
+		return err
+	}
+
+	key.LastUsedAt = &now
+	return nil
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
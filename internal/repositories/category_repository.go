@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"context"
+	"shop/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type CategoryRepository interface {
+	Create(category *models.Category, ctx context.Context) error
+	GetByID(id uint, ctx context.Context) (*models.Category, error)
+	GetAll(ctx context.Context) ([]models.Category, error)
+	GetDescendantIDs(id uint, ctx context.Context) ([]uint, error)
+	CountChildren(id uint, ctx context.Context) (int64, error)
+	CountProducts(id uint, ctx context.Context) (int64, error)
+	Update(category *models.Category, ctx context.Context) error
+	Delete(id uint, ctx context.Context) error
+}
+
+type categoryRepository struct {
+	db *gorm.DB
+}
+
+func (cr *categoryRepository) Create(category *models.Category, ctx context.Context) error {
+	return cr.db.WithContext(ctx).Create(category).Error
+}
+
+func (cr *categoryRepository) GetByID(id uint, ctx context.Context) (*models.Category, error) {
+	var category models.Category
+	err := cr.db.WithContext(ctx).First(&category, id).Error
+	return &category, err
+}
+
+func (cr *categoryRepository) GetAll(ctx context.Context) ([]models.Category, error) {
+	var categories []models.Category
+	err := cr.db.WithContext(ctx).Order("name ASC").Find(&categories).Error
+	return categories, err
+}
+
+// GetDescendantIDs returns the id of the category itself followed by the ids of all
+// its subcategories at any depth.
+func (cr *categoryRepository) GetDescendantIDs(id uint, ctx context.Context) ([]uint, error) {
+	var ids []uint
+	err := cr.db.WithContext(ctx).Raw(`
+		WITH RECURSIVE subcategories AS (
+			SELECT id FROM categories WHERE id = ?
+			UNION ALL
+			SELECT c.id FROM categories c JOIN subcategories s ON c.parent_id = s.id
+		)
+		SELECT id FROM subcategories`, id).
+		Scan(&ids).
+		Error
+	return ids, err
+}
+
+func (cr *categoryRepository) CountChildren(id uint, ctx context.Context) (int64, error) {
+	var count int64
+	err := cr.db.WithContext(ctx).Model(&models.Category{}).Where("parent_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+func (cr *categoryRepository) CountProducts(id uint, ctx context.Context) (int64, error) {
+	var count int64
+	err := cr.db.WithContext(ctx).Model(&models.Product{}).Where("category_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+func (cr *categoryRepository) Update(category *models.Category, ctx context.Context) error {
+	return cr.db.WithContext(ctx).
+		Model(category).
+		Select("name", "parent_id").
+		Updates(category).
+		Error
+}
+
+func (cr *categoryRepository) Delete(id uint, ctx context.Context) error {
+	return cr.db.WithContext(ctx).Delete(&models.Category{}, id).Error
+}
+
+func NewCategoryRepository(db *gorm.DB) CategoryRepository {
+	return &categoryRepository{db: db}
+}
@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"context"
+	"shop/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type EmailVerificationRepository interface {
+	Create(token *models.EmailVerificationToken, ctx context.Context) error
+	FindByHash(hash string, ctx context.Context) (*models.EmailVerificationToken, error)
+	FindLatestByUserID(userID uint, ctx context.Context) (*models.EmailVerificationToken, error)
+	Verify(token *models.EmailVerificationToken, ctx context.Context) error
+}
+
+type emailVerificationRepository struct {
+	db *gorm.DB
+}
+
+func (e *emailVerificationRepository) Create(token *models.EmailVerificationToken, ctx context.Context) error {
+	return e.db.WithContext(ctx).Create(token).Error
+}
+
+func (e *emailVerificationRepository) FindByHash(hash string, ctx context.Context) (*models.EmailVerificationToken, error) {
+	var token models.EmailVerificationToken
+	err := e.db.WithContext(ctx).First(&token, "token_hash = ?", hash).Error
+	return &token, err
+}
+
+func (e *emailVerificationRepository) FindLatestByUserID(userID uint, ctx context.Context) (*models.EmailVerificationToken, error) {
+	var token models.EmailVerificationToken
+	err := e.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		First(&token).
+		Error
+	return &token, err
+}
+
+// Verify marks the email of the token's user as verified and deletes all of the
+// user's verification tokens.
+func (e *emailVerificationRepository) Verify(token *models.EmailVerificationToken, ctx context.Context) error {
+	return e.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).
+			Where("id = ? AND email_verified_at IS NULL", token.UserID).
+			Update("email_verified_at", time.Now()).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", token.UserID).
+			Delete(&models.EmailVerificationToken{}).
+			Error
+	})
+}
+
+func NewEmailVerificationRepository(db *gorm.DB) EmailVerificationRepository {
+	return &emailVerificationRepository{db: db}
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"shop/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginFailureStats summarises the failed logins of an email or an IP. FirstAt and
+// LastAt are nil if there are none.
+type LoginFailureStats struct {
+	Count   int64
+	FirstAt *time.Time
+	LastAt  *time.Time
+}
+
+type LoginAttemptRepository interface {
+	RecordFailure(failure *models.LoginFailure, expiredBefore time.Time, ctx context.Context) error
+	FailuresByEmail(email string, since time.Time, ctx context.Context) (*LoginFailureStats, error)
+	FailuresByIP(ip string, since time.Time, ctx context.Context) (*LoginFailureStats, error)
+	ClearFailures(email string, ctx context.Context) error
+	FindLockout(email string, ctx context.Context) (*models.LoginLockout, error)
+	Lock(lockout *models.LoginLockout, ctx context.Context) error
+	Unlock(email string, ctx context.Context) error
+}
+
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+// RecordFailure stores a failed login. Failures older than expiredBefore don't count
+// anymore and are removed along the way.
+func (l *loginAttemptRepository) RecordFailure(failure *models.LoginFailure, expiredBefore time.Time, ctx context.Context) error {
+	err := l.db.WithContext(ctx).
+		Where("created_at < ?", expiredBefore).
+		Delete(&models.LoginFailure{}).
+		Error
+	if err != nil {
+		return err
+	}
+
+	return l.db.WithContext(ctx).Create(failure).Error
+}
+
+func (l *loginAttemptRepository) FailuresByEmail(email string, since time.Time, ctx context.Context) (*LoginFailureStats, error) {
+	return l.failureStats("email = ? AND created_at >= ?", email, since, ctx)
+}
+
+func (l *loginAttemptRepository) FailuresByIP(ip string, since time.Time, ctx context.Context) (*LoginFailureStats, error) {
+	return l.failureStats("ip = ? AND created_at >= ?", ip, since, ctx)
+}
+
+func (l *loginAttemptRepository) failureStats(query string, value string, since time.Time, ctx context.Context) (*LoginFailureStats, error) {
+	var stats LoginFailureStats
+	err := l.db.WithContext(ctx).
+		Model(&models.LoginFailure{}).
+		Select("COUNT(*) AS count, MIN(created_at) AS first_at, MAX(created_at) AS last_at").
+		Where(query, value, since).
+		Scan(&stats).
+		Error
+	return &stats, err
+}
+
+func (l *loginAttemptRepository) ClearFailures(email string, ctx context.Context) error {
+	return l.db.WithContext(ctx).
+		Where("email = ?", email).
+		Delete(&models.LoginFailure{}).
+		Error
+}
+
+// FindLockout returns the active lockout of the email, or gorm.ErrRecordNotFound if
+// logins with it are allowed.
+func (l *loginAttemptRepository) FindLockout(email string, ctx context.Context) (*models.LoginLockout, error) {
+	var lockout models.LoginLockout
+	err := l.db.WithContext(ctx).
+		Where("email = ? AND locked_until > ?", email, time.Now()).
+		First(&lockout).
+		Error
+	return &lockout, err
+}
+
+// Lock stores the lockout, replacing an expired one of the same email, and clears the
+// failures that led to it.
+func (l *loginAttemptRepository) Lock(lockout *models.LoginLockout, ctx context.Context) error {
+	return l.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"locked_until", "created_at"}),
+		}).Create(lockout).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("email = ?", lockout.Email).
+			Delete(&models.LoginFailure{}).
+			Error
+	})
+}
+
+// Unlock lifts the lockout of the email and clears its failures.
+func (l *loginAttemptRepository) Unlock(email string, ctx context.Context) error {
+	return l.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("email = ?", email).
+			Delete(&models.LoginLockout{}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("email = ?", email).
+			Delete(&models.LoginFailure{}).
+			Error
+	})
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
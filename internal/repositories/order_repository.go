@@ -0,0 +1,200 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"shop/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCartEmpty          = errors.New("cart is empty")
+	ErrOrderStatusChanged = errors.New("order status was changed concurrently")
+	ErrInsufficientStock  = errors.New("not enough stock")
+)
+
+// OrderFilter narrows down the orders returned by OrderRepository.GetAll.
+// Zero values of UserID, SellerID and Status mean no restriction. With SellerID only
+// orders that contain products of the seller are returned, with only those items.
+type OrderFilter struct {
+	UserID   uint
+	SellerID uint
+	Status   string
+	Limit    int
+	Offset   int
+}
+
+func (f OrderFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.UserID != 0 {
+		db = db.Where("user_id = ?", f.UserID)
+	}
+	if f.SellerID != 0 {
+		db = db.Where(
+			"id IN (SELECT order_items.order_id FROM order_items JOIN products ON products.id = order_items.product_id WHERE products.user_id = ?)",
+			f.SellerID,
+		)
+	}
+	if f.Status != "" {
+		db = db.Where("status = ?", f.Status)
+	}
+
+	return db
+}
+
+func (f OrderFilter) preloadItems(db *gorm.DB) *gorm.DB {
+	if f.SellerID != 0 {
+		return db.Preload("Items", "product_id IN (SELECT id FROM products WHERE user_id = ?)", f.SellerID)
+	}
+
+	return db.Preload("Items")
+}
+
+type OrderRepository interface {
+	CreateFromCart(order *models.Order, cartID uint, ctx context.Context) error
+	GetByID(id uint, ctx context.Context) (*models.Order, error)
+	GetAll(filter OrderFilter, ctx context.Context) ([]models.Order, int64, error)
+	UpdateStatus(order *models.Order, status string, actorID uint, restock bool, ctx context.Context) error
+}
+
+type orderRepository struct {
+	db *gorm.DB
+}
+
+// CreateFromCart builds order items from the cart contents, snapshotting the current
+// product prices, takes the ordered quantities from stock, saves the order and empties
+// the cart in a single transaction.
+func (o *orderRepository) CreateFromCart(order *models.Order, cartID uint, ctx context.Context) error {
+	return o.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var cartItems []models.CartItem
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Product").
+			Order("product_id ASC").
+			Find(&cartItems, "cart_id = ?", cartID).
+			Error
+		if err != nil {
+			return err
+		}
+		if len(cartItems) == 0 {
+			return ErrCartEmpty
+		}
+
+		order.Items = make([]models.OrderItem, 0, len(cartItems))
+		order.TotalPrice = 0
+		for _, cartItem := range cartItems {
+			if err := decrementStock(tx, cartItem.ProductID, cartItem.Quantity); err != nil {
+				return err
+			}
+			order.Items = append(order.Items, models.OrderItem{
+				ProductID: cartItem.ProductID,
+				Quantity:  cartItem.Quantity,
+				Price:     cartItem.Product.Price,
+			})
+			order.TotalPrice += cartItem.Product.Price * float64(cartItem.Quantity)
+		}
+		order.TotalPrice = math.Round(order.TotalPrice*100) / 100
+		order.StatusHistory = []models.OrderStatusChange{{ToStatus: order.Status, ActorID: &order.UserID}}
+
+		if err := tx.Create(order).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("cart_id = ?", cartID).Delete(&models.CartItem{}).Error
+	})
+}
+
+func (o *orderRepository) GetByID(id uint, ctx context.Context) (*models.Order, error) {
+	var order models.Order
+	err := o.db.WithContext(ctx).
+		Preload("Items.Product").
+		Preload("StatusHistory", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC, id ASC")
+		}).
+		First(&order, id).
+		Error
+	return &order, err
+}
+
+func (o *orderRepository) GetAll(filter OrderFilter, ctx context.Context) ([]models.Order, int64, error) {
+	var total int64
+	err := o.db.WithContext(ctx).Model(&models.Order{}).Scopes(filter.apply).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var orders []models.Order
+	err = o.db.WithContext(ctx).
+		Scopes(filter.preloadItems, filter.apply).
+		Order("created_at DESC, id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&orders).
+		Error
+	return orders, total, err
+}
+
+// UpdateStatus moves the order to the new status only if it still has the status it was
+// loaded with, and records the transition in the order history. With restock the ordered
+// quantities are returned to stock.
+func (o *orderRepository) UpdateStatus(order *models.Order, status string, actorID uint, restock bool, ctx context.Context) error {
+	return o.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND status = ?", order.ID, order.Status).
+			Update("status", status)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOrderStatusChanged
+		}
+
+		if restock {
+			for _, item := range order.Items {
+				err := tx.Model(&models.Product{}).
+					Where("id = ?", item.ProductID).
+					Update("stock", gorm.Expr("stock + ?", item.Quantity)).
+					Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		change := models.OrderStatusChange{
+			OrderID:    order.ID,
+			FromStatus: order.Status,
+			ToStatus:   status,
+			ActorID:    &actorID,
+		}
+		if err := tx.Create(&change).Error; err != nil {
+			return err
+		}
+
+		order.Status = status
+		order.StatusHistory = append(order.StatusHistory, change)
+		return nil
+	})
+}
+
+// decrementStock takes quantity from the product stock with a conditional update, so
+// concurrent transactions can never take the stock below zero.
+func decrementStock(tx *gorm.DB, productID uint, quantity int) error {
+	result := tx.Model(&models.Product{}).
+		Where("id = ? AND stock >= ?", productID, quantity).
+		Update("stock", gorm.Expr("stock - ?", quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w for product %d", ErrInsufficientStock, productID)
+	}
+
+	return nil
+}
+
+func NewOrderRepository(db *gorm.DB) OrderRepository {
+	return &orderRepository{db: db}
+}
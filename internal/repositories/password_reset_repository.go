@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"shop/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrResetTokenUsed = errors.New("reset token is already used")
+
+type PasswordResetRepository interface {
+	Create(token *models.PasswordResetToken, ctx context.Context) error
+	FindByHash(hash string, ctx context.Context) (*models.PasswordResetToken, error)
+	ResetPassword(token *models.PasswordResetToken, passwordHash string, ctx context.Context) error
+}
+
+type passwordResetRepository struct {
+	db *gorm.DB
+}
+
+// Create stores a new reset token and deletes the unused tokens previously issued to
+// the same user, so only the latest link works.
+func (p *passwordResetRepository) Create(token *models.PasswordResetToken, ctx context.Context) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Delete(&models.PasswordResetToken{}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(token).Error
+	})
+}
+
+func (p *passwordResetRepository) FindByHash(hash string, ctx context.Context) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	err := p.db.WithContext(ctx).First(&token, "token_hash = ?", hash).Error
+	return &token, err
+}
+
+// ResetPassword marks the token as used, sets the new password of its user and revokes
+// every session of the user. It returns ErrResetTokenUsed if the token has been used
+// by a concurrent request.
+func (p *passwordResetRepository) ResetPassword(token *models.PasswordResetToken, passwordHash string, ctx context.Context) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrResetTokenUsed
+		}
+
+		err := tx.Model(&models.User{}).
+			Where("id = ?", token.UserID).
+			Updates(map[string]any{
+				"password":      passwordHash,
+				"token_version": gorm.Expr("token_version + 1"),
+			}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", token.UserID).
+			Update("revoked_at", now).
+			Error
+		if err != nil {
+			return err
+		}
+
+		token.UsedAt = &now
+		return nil
+	})
+}
+
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
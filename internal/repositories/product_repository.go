@@ -2,17 +2,60 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"shop/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var ErrStockChanged = errors.New("stock has changed since it was read")
+
+// ProductFilter narrows down and orders the products returned by ProductRepository.GetAll.
+// Zero values mean no restriction.
+type ProductFilter struct {
+	CategoryIDs []uint
+	SellerID    uint
+	MinPrice    *float64
+	MaxPrice    *float64
+	SortBy      string
+	Desc        bool
+	Limit       int
+	Offset      int
+}
+
+var productSortColumns = map[string]string{
+	"price":      "price",
+	"created_at": "created_at",
+	"name":       "name",
+}
+
+func (f ProductFilter) apply(db *gorm.DB) *gorm.DB {
+	if len(f.CategoryIDs) > 0 {
+		db = db.Where("category_id IN ?", f.CategoryIDs)
+	}
+	if f.SellerID != 0 {
+		db = db.Where("user_id = ?", f.SellerID)
+	}
+	if f.MinPrice != nil {
+		db = db.Where("price >= ?", *f.MinPrice)
+	}
+	if f.MaxPrice != nil {
+		db = db.Where("price <= ?", *f.MaxPrice)
+	}
+
+	return db
+}
+
 type ProductRepository interface {
 	CreateProduct(product *models.Product, ctx context.Context) error
 	GetProduct(id uint, ctx context.Context) (*models.Product, error)
-	GetAll(ctx context.Context) ([]models.Product, error)
-	GetAllBySellerID(sellerID uint, ctx context.Context) ([]models.Product, error)
+	GetByIDs(ids []uint, ctx context.Context) ([]models.Product, error)
+	GetAll(filter ProductFilter, ctx context.Context) ([]models.Product, int64, error)
 	UpdateProduct(product *models.Product, ctx context.Context) error
+	UpdateStock(id uint, stock int, expected *int, ctx context.Context) error
+	AdjustStock(id uint, delta int, ctx context.Context) (int, error)
 	DeleteProduct(id uint, ctx context.Context) error
 }
 
@@ -30,20 +73,86 @@ func (p *productRepository) GetProduct(id uint, ctx context.Context) (*models.Pr
 	return &product, err
 }
 
-func (p *productRepository) GetAll(ctx context.Context) ([]models.Product, error) {
+func (p *productRepository) GetByIDs(ids []uint, ctx context.Context) ([]models.Product, error) {
 	var products []models.Product
-	err := p.db.WithContext(ctx).Find(&products).Error
+	err := p.db.WithContext(ctx).Find(&products, "id IN ?", ids).Error
 	return products, err
 }
 
-func (p *productRepository) GetAllBySellerID(sellerID uint, ctx context.Context) ([]models.Product, error) {
+func (p *productRepository) GetAll(filter ProductFilter, ctx context.Context) ([]models.Product, int64, error) {
+	var total int64
+	err := p.db.WithContext(ctx).Model(&models.Product{}).Scopes(filter.apply).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	column, ok := productSortColumns[filter.SortBy]
+	if !ok {
+		column = "created_at"
+	}
+	query := p.db.WithContext(ctx).
+		Scopes(filter.apply).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: filter.Desc}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: filter.Desc})
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit).Offset(filter.Offset)
+	}
+
 	var products []models.Product
-	err := p.db.WithContext(ctx).Find(&products, "user_id = ?", sellerID).Error
-	return products, err
+	err = query.Find(&products).Error
+	return products, total, err
 }
 
+// UpdateProduct saves the product details. Stock is left alone, it only changes through
+// UpdateStock and orders, so a concurrent checkout is never overwritten.
 func (p *productRepository) UpdateProduct(product *models.Product, ctx context.Context) error {
-	return p.db.WithContext(ctx).Save(product).Error
+	return p.db.WithContext(ctx).Model(product).Select("*").Omit("stock").Updates(product).Error
+}
+
+// UpdateStock sets the stock of the product. With expected it is only set if the stock
+// still is the expected quantity, so changes made by orders since it was read are not
+// overwritten.
+func (p *productRepository) UpdateStock(id uint, stock int, expected *int, ctx context.Context) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		current, err := lockStock(tx, id)
+		if err != nil {
+			return err
+		}
+		if expected != nil && current != *expected {
+			return ErrStockChanged
+		}
+
+		return tx.Model(&models.Product{}).Where("id = ?", id).Update("stock", stock).Error
+	})
+}
+
+// AdjustStock adds delta, which may be negative, to the stock of the product and returns
+// the new stock. The stock never goes below zero.
+func (p *productRepository) AdjustStock(id uint, delta int, ctx context.Context) (int, error) {
+	var stock int
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		current, err := lockStock(tx, id)
+		if err != nil {
+			return err
+		}
+		stock = current + delta
+		if stock < 0 {
+			return fmt.Errorf("%w for product %d", ErrInsufficientStock, id)
+		}
+
+		return tx.Model(&models.Product{}).Where("id = ?", id).Update("stock", stock).Error
+	})
+	return stock, err
+}
+
+// lockStock reads the stock of the product and locks it until the transaction ends.
+func lockStock(tx *gorm.DB, id uint) (int, error) {
+	var product models.Product
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "stock").
+		First(&product, id).
+		Error
+	return product.Stock, err
 }
 
 func (p *productRepository) DeleteProduct(id uint, ctx context.Context) error {
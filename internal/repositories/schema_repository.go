@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// SchemaRepository gives access to the state of the database itself rather than to
+// the data in it.
+type SchemaRepository interface {
+	Ping(ctx context.Context) error
+	// GetVersion returns the version of the last applied migration and whether it
+	// failed halfway.
+	GetVersion(ctx context.Context) (uint, bool, error)
+}
+
+type schemaRepository struct {
+	db *gorm.DB
+}
+
+func (s *schemaRepository) Ping(ctx context.Context) error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.PingContext(ctx)
+}
+
+func (s *schemaRepository) GetVersion(ctx context.Context) (uint, bool, error) {
+	var migration struct {
+		Version uint
+		Dirty   bool
+	}
+	err := s.db.WithContext(ctx).
+		Table("schema_migrations").
+		Select("version", "dirty").
+		Take(&migration).
+		Error
+	return migration.Version, migration.Dirty, err
+}
+
+func NewSchemaRepository(db *gorm.DB) SchemaRepository {
+	return &schemaRepository{db: db}
+}
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"shop/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrApplicationReviewed = errors.New("application is already reviewed")
+
+// SellerApplicationFilter narrows down the applications returned by
+// SellerApplicationRepository.GetAll. Zero values of UserID and Status mean no restriction.
+type SellerApplicationFilter struct {
+	UserID uint
+	Status string
+	Limit  int
+	Offset int
+}
+
+func (f SellerApplicationFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.UserID != 0 {
+		db = db.Where("user_id = ?", f.UserID)
+	}
+	if f.Status != "" {
+		db = db.Where("status = ?", f.Status)
+	}
+
+	return db
+}
+
+type SellerApplicationRepository interface {
+	Create(application *models.SellerApplication, ctx context.Context) error
+	GetByID(id uint, ctx context.Context) (*models.SellerApplication, error)
+	GetAll(filter SellerApplicationFilter, ctx context.Context) ([]models.SellerApplication, int64, error)
+	Review(application *models.SellerApplication, status string, reviewerID uint, userType string, ctx context.Context) error
+}
+
+type sellerApplicationRepository struct {
+	db *gorm.DB
+}
+
+func (s *sellerApplicationRepository) Create(application *models.SellerApplication, ctx context.Context) error {
+	return s.db.WithContext(ctx).Create(application).Error
+}
+
+func (s *sellerApplicationRepository) GetByID(id uint, ctx context.Context) (*models.SellerApplication, error) {
+	var application models.SellerApplication
+	err := s.db.WithContext(ctx).First(&application, id).Error
+	return &application, err
+}
+
+func (s *sellerApplicationRepository) GetAll(filter SellerApplicationFilter, ctx context.Context) ([]models.SellerApplication, int64, error) {
+	var total int64
+	err := s.db.WithContext(ctx).Model(&models.SellerApplication{}).Scopes(filter.apply).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var applications []models.SellerApplication
+	err = s.db.WithContext(ctx).
+		Scopes(filter.apply).
+		Order("created_at DESC, id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&applications).
+		Error
+	return applications, total, err
+}
+
+// Review sets the status of a pending application and, when userType is not empty,
+// changes the type of the applicant in the same transaction.
+func (s *sellerApplicationRepository) Review(application *models.SellerApplication, status string, reviewerID uint, userType string, ctx context.Context) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.SellerApplication{}).
+			Where("id = ? AND status = ?", application.ID, application.Status).
+			Updates(map[string]any{"status": status, "reviewer_id": reviewerID, "reviewed_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrApplicationReviewed
+		}
+
+		if userType != "" {
+			err := tx.Model(&models.User{}).
+				Where("id = ?", application.UserID).
+				Update("type", userType).
+				Error
+			if err != nil {
+				return err
+			}
+		}
+
+		application.Status = status
+		application.ReviewerID = &reviewerID
+		application.ReviewedAt = &now
+		return nil
+	})
+}
+
+func NewSellerApplicationRepository(db *gorm.DB) SellerApplicationRepository {
+	return &sellerApplicationRepository{db: db}
+}
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"shop/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrRefreshTokenRevoked = errors.New("refresh token is revoked")
+
+type TokenRepository interface {
+	CreateRefreshToken(token *models.RefreshToken, ctx context.Context) error
+	FindRefreshTokenByHash(hash string, ctx context.Context) (*models.RefreshToken, error)
+	RotateRefreshToken(old *models.RefreshToken, next *models.RefreshToken, ctx context.Context) error
+	RevokeRefreshTokenFamily(familyID string, ctx context.Context) error
+	RevokeAllUserTokens(userID uint, ctx context.Context) error
+	RevokeAccessToken(jti string, expiresAt time.Time, ctx context.Context) error
+	IsAccessTokenRevoked(jti string, ctx context.Context) (bool, error)
+}
+
+type tokenRepository struct {
+	db *gorm.DB
+}
+
+func (t *tokenRepository) CreateRefreshToken(token *models.RefreshToken, ctx context.Context) error {
+	return t.db.WithContext(ctx).Create(token).Error
+}
+
+func (t *tokenRepository) FindRefreshTokenByHash(hash string, ctx context.Context) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := t.db.WithContext(ctx).First(&token, "token_hash = ?", hash).Error
+	return &token, err
+}
+
+// RotateRefreshToken revokes old and stores next in its place. It returns
+// ErrRefreshTokenRevoked if old has already been revoked by a concurrent request.
+func (t *tokenRepository) RotateRefreshToken(old *models.RefreshToken, next *models.RefreshToken, ctx context.Context) error {
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", old.ID).
+			Update("revoked_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenRevoked
+		}
+
+		old.RevokedAt = &now
+		return tx.Create(next).Error
+	})
+}
+
+func (t *tokenRepository) RevokeRefreshTokenFamily(familyID string, ctx context.Context) error {
+	return t.db.WithContext(ctx).
+		Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).
+		Error
+}
+
+// RevokeAllUserTokens revokes every refresh token of the user and bumps the user's
+// token version, which invalidates all access tokens issued so far.
+func (t *tokenRepository) RevokeAllUserTokens(userID uint, ctx context.Context) error {
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", time.Now()).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.User{}).
+			Where("id = ?", userID).
+			Update("token_version", gorm.Expr("token_version + 1")).
+			Error
+	})
+}
+
+// RevokeAccessToken blacklists the access token until it expires. Entries of tokens
+// that have already expired are not needed anymore and are removed along the way.
+func (t *tokenRepository) RevokeAccessToken(jti string, expiresAt time.Time, ctx context.Context) error {
+	err := t.db.WithContext(ctx).
+		Where("expires_at < ?", time.Now()).
+		Delete(&models.RevokedToken{}).
+		Error
+	if err != nil {
+		return err
+	}
+
+	return t.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RevokedToken{JTI: jti, ExpiresAt: expiresAt}).
+		Error
+}
+
+func (t *tokenRepository) IsAccessTokenRevoked(jti string, ctx context.Context) (bool, error) {
+	var count int64
+	err := t.db.WithContext(ctx).
+		Model(&models.RevokedToken{}).
+		Where("jti = ?", jti).
+		Count(&count).
+		Error
+	return count > 0, err
+}
+
+func NewTokenRepository(db *gorm.DB) TokenRepository {
+	return &tokenRepository{db: db}
+}
@@ -0,0 +1,196 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"shop/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrTOTPStepUsed            = errors.New("totp code is already used")
+	ErrRecoveryCodeNotFound    = errors.New("recovery code not found")
+	ErrLoginChallengeUsed      = errors.New("login challenge is already used")
+	ErrLoginChallengeExhausted = errors.New("login challenge has no attempts left")
+)
+
+type TwoFactorRepository interface {
+	FindCredential(userID uint, ctx context.Context) (*models.TOTPCredential, error)
+	SaveCredential(credential *models.TOTPCredential, ctx context.Context) error
+	Confirm(credential *models.TOTPCredential, step int64, codes []models.RecoveryCode, ctx context.Context) error
+	Disable(userID uint, ctx context.Context) error
+	UseStep(userID uint, step int64, ctx context.Context) error
+	UseRecoveryCode(userID uint, hash string, ctx context.Context) error
+	ReplaceRecoveryCodes(userID uint, codes []models.RecoveryCode, ctx context.Context) error
+
+	CreateChallenge(challenge *models.LoginChallenge, ctx context.Context) error
+	FindChallengeByHash(hash string, ctx context.Context) (*models.LoginChallenge, error)
+	AddChallengeAttempt(challenge *models.LoginChallenge, maxAttempts int, ctx context.Context) error
+	ConsumeChallenge(challenge *models.LoginChallenge, ctx context.Context) error
+}
+
+type twoFactorRepository struct {
+	db *gorm.DB
+}
+
+func (t *twoFactorRepository) FindCredential(userID uint, ctx context.Context) (*models.TOTPCredential, error) {
+	var credential models.TOTPCredential
+	err := t.db.WithContext(ctx).First(&credential, "user_id = ?", userID).Error
+	return &credential, err
+}
+
+// SaveCredential stores a new unconfirmed credential in place of the previous one of
+// the user.
+func (t *twoFactorRepository) SaveCredential(credential *models.TOTPCredential, ctx context.Context) error {
+	return t.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"secret", "confirmed_at", "last_used_step", "created_at"}),
+		}).
+		Create(credential).
+		Error
+}
+
+// Confirm enables two-factor authentication with the credential, whose code of the
+// given step has just been accepted, and replaces the recovery codes of the user.
+func (t *twoFactorRepository) Confirm(credential *models.TOTPCredential, step int64, codes []models.RecoveryCode, ctx context.Context) error {
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Model(&models.TOTPCredential{}).
+			Where("user_id = ?", credential.UserID).
+			Updates(map[string]any{"confirmed_at": now, "last_used_step": step}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		if err := replaceRecoveryCodes(tx, credential.UserID, codes); err != nil {
+			return err
+		}
+
+		credential.ConfirmedAt = &now
+		credential.LastUsedStep = step
+		return nil
+	})
+}
+
+// Disable removes the credential, the recovery codes and the pending login challenges
+// of the user.
+func (t *twoFactorRepository) Disable(userID uint, ctx context.Context) error {
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, model := range []any{&models.LoginChallenge{}, &models.RecoveryCode{}, &models.TOTPCredential{}} {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// UseStep records that the code of the step has been accepted. It returns
+// ErrTOTPStepUsed if a code of the same or a later step has been accepted before.
+func (t *twoFactorRepository) UseStep(userID uint, step int64, ctx context.Context) error {
+	result := t.db.WithContext(ctx).
+		Model(&models.TOTPCredential{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTOTPStepUsed
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks the unused recovery code with the hash as used. It returns
+// ErrRecoveryCodeNotFound if the user has no such code.
+func (t *twoFactorRepository) UseRecoveryCode(userID uint, hash string, ctx context.Context) error {
+	result := t.db.WithContext(ctx).
+		Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+
+	return nil
+}
+
+func (t *twoFactorRepository) ReplaceRecoveryCodes(userID uint, codes []models.RecoveryCode, ctx context.Context) error {
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codes []models.RecoveryCode) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(&codes).Error
+}
+
+// CreateChallenge stores a new login challenge. Challenges that have expired are not
+// needed anymore and are removed along the way.
+func (t *twoFactorRepository) CreateChallenge(challenge *models.LoginChallenge, ctx context.Context) error {
+	err := t.db.WithContext(ctx).
+		Where("expires_at < ?", time.Now()).
+		Delete(&models.LoginChallenge{}).
+		Error
+	if err != nil {
+		return err
+	}
+
+	return t.db.WithContext(ctx).Create(challenge).Error
+}
+
+func (t *twoFactorRepository) FindChallengeByHash(hash string, ctx context.Context) (*models.LoginChallenge, error) {
+	var challenge models.LoginChallenge
+	err := t.db.WithContext(ctx).First(&challenge, "token_hash = ?", hash).Error
+	return &challenge, err
+}
+
+// AddChallengeAttempt counts an attempt to pass the challenge. The count is checked and
+// incremented in a single statement, so concurrent requests can't exceed maxAttempts.
+// It returns ErrLoginChallengeExhausted if no attempt is left or the challenge is gone.
+func (t *twoFactorRepository) AddChallengeAttempt(challenge *models.LoginChallenge, maxAttempts int, ctx context.Context) error {
+	result := t.db.WithContext(ctx).
+		Model(&models.LoginChallenge{}).
+		Where("id = ? AND attempts < ?", challenge.ID, maxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLoginChallengeExhausted
+	}
+
+	challenge.Attempts++
+	return nil
+}
+
+// ConsumeChallenge deletes the challenge once it has been passed. It returns
+// ErrLoginChallengeUsed if it has been passed by a concurrent request.
+func (t *twoFactorRepository) ConsumeChallenge(challenge *models.LoginChallenge, ctx context.Context) error {
+	result := t.db.WithContext(ctx).Delete(&models.LoginChallenge{}, challenge.ID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLoginChallengeUsed
+	}
+
+	return nil
+}
+
+func NewTwoFactorRepository(db *gorm.DB) TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
@@ -3,14 +3,54 @@ package repositories
 import (
 	"context"
 	"shop/internal/models"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+// UserFilter narrows down the users returned by UserRepository.GetAll. Query matches
+// a part of the username or email and Locked keeps only users whose login is locked
+// out. Zero values mean no restriction.
+type UserFilter struct {
+	Query  string
+	Type   string
+	Locked bool
+	Limit  int
+	Offset int
+}
+
+func (f UserFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.Query != "" {
+		pattern := "%" + likeEscaper.Replace(f.Query) + "%"
+		db = db.Where("users.username LIKE ? OR users.email LIKE ?", pattern, pattern)
+	}
+	if f.Type != "" {
+		db = db.Where("users.type = ?", f.Type)
+	}
+	if f.Locked {
+		db = db.Where("users.email IN (SELECT email FROM login_lockouts WHERE locked_until > ?)", time.Now())
+	}
+
+	return db
+}
+
+// withLockout loads User.LockedUntil from the active lockout of the user's email.
+func withLockout(db *gorm.DB) *gorm.DB {
+	return db.
+		Select("users.*, login_lockouts.locked_until").
+		Joins("LEFT JOIN login_lockouts ON login_lockouts.email = users.email AND login_lockouts.locked_until > ?", time.Now())
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 type UserRepository interface {
 	Insert(user *models.User, ctx context.Context) error
 	FindByID(id uint, ctx context.Context) (*models.User, error)
 	FindByEmail(email string, ctx context.Context) (*models.User, error)
+	GetAll(filter UserFilter, ctx context.Context) ([]models.User, int64, error)
+	UpdateType(id uint, userType string, ctx context.Context) error
+	UpdateBannedAt(id uint, bannedAt *time.Time, ctx context.Context) error
 }
 
 type userRepository struct {
@@ -23,7 +63,7 @@ func (u *userRepository) Insert(user *models.User, ctx context.Context) error {
 
 func (u *userRepository) FindByID(id uint, ctx context.Context) (*models.User, error) {
 	var user models.User
-	err := u.db.WithContext(ctx).Preload("Cart").First(&user, id).Error
+	err := u.db.WithContext(ctx).Preload("Cart").Scopes(withLockout).First(&user, id).Error
 	return &user, err
 }
 
@@ -33,6 +73,40 @@ func (u *userRepository) FindByEmail(email string, ctx context.Context) (*models
 	return &user, err
 }
 
+func (u *userRepository) GetAll(filter UserFilter, ctx context.Context) ([]models.User, int64, error) {
+	var total int64
+	err := u.db.WithContext(ctx).Model(&models.User{}).Scopes(filter.apply).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	err = u.db.WithContext(ctx).
+		Scopes(filter.apply, withLockout).
+		Order("users.id ASC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&users).
+		Error
+	return users, total, err
+}
+
+func (u *userRepository) UpdateType(id uint, userType string, ctx context.Context) error {
+	return u.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ?", id).
+		Update("type", userType).
+		Error
+}
+
+func (u *userRepository) UpdateBannedAt(id uint, bannedAt *time.Time, ctx context.Context) error {
+	return u.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ?", id).
+		Update("banned_at", bannedAt).
+		Error
+}
+
 func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
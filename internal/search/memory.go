@@ -0,0 +1,146 @@
+package search
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"shop/internal/models"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	nameWeight        = 2
+	descriptionWeight = 1
+)
+
+type document struct {
+	name        string
+	description string
+}
+
+// MemoryIndex is an in-process inverted index. It tolerates typos and unfinished words
+// in the query and is meant for local development and tests.
+type MemoryIndex struct {
+	mu        sync.RWMutex
+	documents map[uint]document
+	// postings maps a word to the products containing it and the weighted number of
+	// its occurrences in each of them.
+	postings map[string]map[uint]float64
+}
+
+func (mi *MemoryIndex) Index(product *models.Product, ctx context.Context) error {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	mi.remove(product.ID)
+	mi.documents[product.ID] = document{name: product.Name, description: product.Description}
+	mi.add(product.ID, product.Name, nameWeight)
+	mi.add(product.ID, product.Description, descriptionWeight)
+
+	return nil
+}
+
+func (mi *MemoryIndex) Remove(productID uint, ctx context.Context) error {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	mi.remove(productID)
+	return nil
+}
+
+func (mi *MemoryIndex) Search(query string, limit, offset int, ctx context.Context) (*Result, error) {
+	terms := slices.Compact(slices.Sorted(slices.Values(tokenize(query))))
+	if len(terms) == 0 {
+		return &Result{}, nil
+	}
+
+	mi.mu.RLock()
+	defer mi.mu.RUnlock()
+
+	scores := make(map[uint]float64)
+	matched := make(map[string]bool)
+	for _, term := range terms {
+		for word, products := range mi.postings {
+			weight := matchWeight(term, word)
+			if weight == 0 {
+				continue
+			}
+			matched[word] = true
+			idf := math.Log(1 + float64(len(mi.documents))/float64(len(products)))
+			for id, frequency := range products {
+				scores[id] += weight * idf * frequency / (frequency + 1)
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ProductID: id, Score: score})
+	}
+	slices.SortFunc(hits, func(a, b Hit) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ProductID, b.ProductID)
+	})
+
+	total := int64(len(hits))
+	hits = hits[min(offset, len(hits)):min(offset+limit, len(hits))]
+	for i := range hits {
+		doc := mi.documents[hits[i].ProductID]
+		hits[i].Highlights = highlights(doc.name, doc.description, func(word string) bool {
+			return matched[word]
+		})
+	}
+
+	return &Result{Hits: hits, Total: total}, nil
+}
+
+func (mi *MemoryIndex) add(productID uint, text string, weight float64) {
+	for _, word := range tokenize(text) {
+		if mi.postings[word] == nil {
+			mi.postings[word] = make(map[uint]float64)
+		}
+		mi.postings[word][productID] += weight
+	}
+}
+
+func (mi *MemoryIndex) remove(productID uint) {
+	doc, ok := mi.documents[productID]
+	if !ok {
+		return
+	}
+
+	for _, word := range tokenize(doc.name + " " + doc.description) {
+		delete(mi.postings[word], productID)
+		if len(mi.postings[word]) == 0 {
+			delete(mi.postings, word)
+		}
+	}
+	delete(mi.documents, productID)
+}
+
+// matchWeight tells how well an indexed word matches a query term: exact matches weigh
+// the most, then words starting with the term, then words within a few typos of it.
+func matchWeight(term, word string) float64 {
+	if word == term {
+		return 1
+	}
+	if len(term) >= 3 && strings.HasPrefix(word, term) {
+		return 0.8
+	}
+	if limit := maxTypos(term); limit > 0 && levenshtein(term, word, limit) <= limit {
+		return 0.5
+	}
+
+	return 0
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		documents: make(map[uint]document),
+		postings:  make(map[string]map[uint]float64),
+	}
+}
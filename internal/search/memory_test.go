@@ -0,0 +1,186 @@
+package search
+
+import (
+	"context"
+	"shop/internal/models"
+	"slices"
+	"testing"
+)
+
+func newTestIndex(t *testing.T, products ...models.Product) *MemoryIndex {
+	t.Helper()
+	index := NewMemoryIndex()
+	for i := range products {
+		if err := index.Index(&products[i], context.Background()); err != nil {
+			t.Fatalf("Index(%d) failed: %v", products[i].ID, err)
+		}
+	}
+
+	return index
+}
+
+func search(t *testing.T, index *MemoryIndex, query string, limit, offset int) *Result {
+	t.Helper()
+	result, err := index.Search(query, limit, offset, context.Background())
+	if err != nil {
+		t.Fatalf("Search(%q) failed: %v", query, err)
+	}
+
+	return result
+}
+
+func ids(hits []Hit) []uint {
+	result := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		result = append(result, hit.ProductID)
+	}
+
+	return result
+}
+
+func TestMemoryIndexRanking(t *testing.T) {
+	index := newTestIndex(t,
+		models.Product{ID: 1, Name: "Kitchen table", Description: "Oak table with a lamp shelf"},
+		models.Product{ID: 2, Name: "Desk lamp", Description: "LED lamp for reading"},
+		models.Product{ID: 3, Name: "Chair", Description: "Goes well with a lamp"},
+		models.Product{ID: 4, Name: "Sofa", Description: "Three seats"},
+	)
+
+	// Product 2 mentions the lamp in its name and description, products 1 and 3 score
+	// the same and are ordered by id.
+	result := search(t, index, "lamp", 10, 0)
+	if want := []uint{2, 1, 3}; !slices.Equal(ids(result.Hits), want) {
+		t.Fatalf("hits = %v, want %v", ids(result.Hits), want)
+	}
+	if result.Total != 3 {
+		t.Errorf("total = %d, want 3", result.Total)
+	}
+	for i := 1; i < len(result.Hits); i++ {
+		if result.Hits[i-1].Score < result.Hits[i].Score {
+			t.Errorf("hits are not ordered by score: %v", result.Hits)
+		}
+	}
+}
+
+func TestMemoryIndexNameOutweighsDescription(t *testing.T) {
+	index := newTestIndex(t,
+		models.Product{ID: 1, Name: "Table", Description: "Comes with a free lamp"},
+		models.Product{ID: 2, Name: "Lamp", Description: "Bright"},
+	)
+
+	result := search(t, index, "lamp", 10, 0)
+	if want := []uint{2, 1}; !slices.Equal(ids(result.Hits), want) {
+		t.Errorf("hits = %v, want %v", ids(result.Hits), want)
+	}
+}
+
+func TestMemoryIndexMatching(t *testing.T) {
+	index := newTestIndex(t,
+		models.Product{ID: 1, Name: "Keyboard", Description: "Mechanical switches"},
+		models.Product{ID: 2, Name: "Mouse", Description: "Wireless"},
+		models.Product{ID: 3, Name: "Monitor", Description: "27 inch display"},
+	)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []uint
+	}{
+		{"exact", "mouse", []uint{2}},
+		{"case insensitive", "MOUSE", []uint{2}},
+		{"prefix", "keyb", []uint{1}},
+		{"prefix in description", "mech", []uint{1}},
+		{"typo", "keybaord", []uint{1}},
+		{"missing letter", "monitr", []uint{3}},
+		{"short terms need an exact match", "mo", nil},
+		{"unrelated", "printer", nil},
+		{"empty", "  ", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := search(t, index, tt.query, 10, 0)
+			if got := ids(result.Hits); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryIndexExactMatchOutranksTypo(t *testing.T) {
+	index := newTestIndex(t,
+		models.Product{ID: 1, Name: "Boots"},
+		models.Product{ID: 2, Name: "Boot"},
+	)
+
+	result := search(t, index, "boot", 10, 0)
+	if want := []uint{2, 1}; !slices.Equal(ids(result.Hits), want) {
+		t.Errorf("hits = %v, want %v", ids(result.Hits), want)
+	}
+}
+
+func TestMemoryIndexHighlights(t *testing.T) {
+	index := newTestIndex(t,
+		models.Product{ID: 1, Name: "Lamp <img src=x onerror=alert(1)>", Description: "A <b>bright</b> lamp"},
+	)
+
+	result := search(t, index, "lamp", 10, 0)
+	if len(result.Hits) != 1 {
+		t.Fatalf("got %d hits, want 1", len(result.Hits))
+	}
+	highlights := result.Hits[0].Highlights
+	if want := "<em>Lamp</em> &lt;img src=x onerror=alert(1)&gt;"; highlights["name"] != want {
+		t.Errorf("name highlight = %q, want %q", highlights["name"], want)
+	}
+	if want := "A &lt;b&gt;bright&lt;/b&gt; <em>lamp</em>"; highlights["description"] != want {
+		t.Errorf("description highlight = %q, want %q", highlights["description"], want)
+	}
+}
+
+func TestMemoryIndexPagination(t *testing.T) {
+	var products []models.Product
+	for id := uint(1); id <= 5; id++ {
+		products = append(products, models.Product{ID: id, Name: "Cable"})
+	}
+	index := newTestIndex(t, products...)
+
+	tests := []struct {
+		limit, offset int
+		want          []uint
+	}{
+		{2, 0, []uint{1, 2}},
+		{2, 2, []uint{3, 4}},
+		{2, 4, []uint{5}},
+		{2, 6, nil},
+		{10, 0, []uint{1, 2, 3, 4, 5}},
+	}
+	for _, tt := range tests {
+		result := search(t, index, "cable", tt.limit, tt.offset)
+		if got := ids(result.Hits); !slices.Equal(got, tt.want) {
+			t.Errorf("limit %d offset %d: hits = %v, want %v", tt.limit, tt.offset, got, tt.want)
+		}
+		if result.Total != 5 {
+			t.Errorf("limit %d offset %d: total = %d, want 5", tt.limit, tt.offset, result.Total)
+		}
+	}
+}
+
+func TestMemoryIndexReindexAndRemove(t *testing.T) {
+	index := newTestIndex(t, models.Product{ID: 1, Name: "Old name"})
+
+	if err := index.Index(&models.Product{ID: 1, Name: "New name"}, context.Background()); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if hits := search(t, index, "old", 10, 0).Hits; len(hits) != 0 {
+		t.Errorf("reindexed product still matches its old name: %v", ids(hits))
+	}
+	if hits := search(t, index, "new", 10, 0).Hits; !slices.Equal(ids(hits), []uint{1}) {
+		t.Errorf("reindexed product doesn't match its new name: %v", ids(hits))
+	}
+
+	if err := index.Remove(1, context.Background()); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if result := search(t, index, "name", 10, 0); len(result.Hits) != 0 || result.Total != 0 {
+		t.Errorf("removed product still found: %v", ids(result.Hits))
+	}
+}
@@ -0,0 +1,85 @@
+package search
+
+import (
+	"context"
+	"shop/internal/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// MySQLIndex searches products with the FULLTEXT index on products(name, description).
+// MySQL keeps the index up to date by itself, so Index and Remove do nothing. Words of
+// the query also match longer words starting with them, but typos are not tolerated.
+type MySQLIndex struct {
+	db *gorm.DB
+}
+
+func (mi *MySQLIndex) Index(product *models.Product, ctx context.Context) error {
+	return nil
+}
+
+func (mi *MySQLIndex) Remove(productID uint, ctx context.Context) error {
+	return nil
+}
+
+func (mi *MySQLIndex) Search(query string, limit, offset int, ctx context.Context) (*Result, error) {
+	terms := tokenize(query)
+	if len(terms) == 0 {
+		return &Result{}, nil
+	}
+	// Terms contain only letters and digits, so they can't carry boolean mode operators.
+	against := strings.Join(terms, "* ") + "*"
+
+	var total int64
+	err := mi.db.WithContext(ctx).
+		Model(&models.Product{}).
+		Where("MATCH(name, description) AGAINST (? IN BOOLEAN MODE)", against).
+		Count(&total).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		ID          uint
+		Name        string
+		Description string
+		Score       float64
+	}
+	err = mi.db.WithContext(ctx).
+		Model(&models.Product{}).
+		Select("id, name, description, MATCH(name, description) AGAINST (? IN BOOLEAN MODE) AS score", against).
+		Where("MATCH(name, description) AGAINST (? IN BOOLEAN MODE)", against).
+		Order("score DESC, id ASC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&rows).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	match := func(word string) bool {
+		for _, term := range terms {
+			if strings.HasPrefix(word, term) {
+				return true
+			}
+		}
+		return false
+	}
+	hits := make([]Hit, 0, len(rows))
+	for _, row := range rows {
+		hits = append(hits, Hit{
+			ProductID:  row.ID,
+			Score:      row.Score,
+			Highlights: highlights(row.Name, row.Description, match),
+		})
+	}
+
+	return &Result{Hits: hits, Total: total}, nil
+}
+
+func NewMySQLIndex(db *gorm.DB) *MySQLIndex {
+	return &MySQLIndex{db: db}
+}
@@ -0,0 +1,27 @@
+package search
+
+import (
+	"context"
+	"shop/internal/models"
+)
+
+// Hit is a single product matched by a search query.
+type Hit struct {
+	ProductID uint
+	Score     float64
+	// Highlights maps a product field name to a fragment of its text with the
+	// matched words wrapped in <em> tags and the rest of the text HTML escaped.
+	Highlights map[string]string
+}
+
+type Result struct {
+	Hits  []Hit
+	Total int64
+}
+
+// SearchIndex finds products by text. Hits are ordered by relevance, most relevant first.
+type SearchIndex interface {
+	Index(product *models.Product, ctx context.Context) error
+	Remove(productID uint, ctx context.Context) error
+	Search(query string, limit, offset int, ctx context.Context) (*Result, error)
+}
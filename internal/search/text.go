@@ -0,0 +1,141 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const fragmentLength = 160
+
+// tokenize splits text into lower-cased words made of letters and digits.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// highlight wraps the words of text accepted by match in <em> tags and returns a
+// fragment of at most fragmentLength runes around the first of them. The rest of the
+// text is HTML escaped, so the fragment is safe to render as HTML. It returns an empty
+// string when no word matches.
+func highlight(text string, match func(word string) bool) string {
+	type span struct{ start, end int }
+	var spans []span
+	start := -1
+	for i, r := range text + " " {
+		isWordRune := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWordRune && start < 0 {
+			start = i
+		}
+		if !isWordRune && start >= 0 {
+			if match(strings.ToLower(text[start:i])) {
+				spans = append(spans, span{start, i})
+			}
+			start = -1
+		}
+	}
+	if len(spans) == 0 {
+		return ""
+	}
+
+	from, to := 0, len(text)
+	if utf8.RuneCountInString(text) > fragmentLength {
+		from = max(0, spans[0].start-fragmentLength/4)
+		for from > 0 && !utf8.RuneStart(text[from]) {
+			from--
+		}
+		to = from
+		for n := 0; to < len(text) && n < fragmentLength; n++ {
+			_, size := utf8.DecodeRuneInString(text[to:])
+			to += size
+		}
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, s := range spans {
+		if s.start < from || s.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:s.start]))
+		b.WriteString("<em>")
+		b.WriteString(html.EscapeString(text[s.start:s.end]))
+		b.WriteString("</em>")
+		pos = s.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+
+	return b.String()
+}
+
+// levenshtein returns the edit distance between a and b, giving up with limit+1 as soon
+// as the distance is known to exceed limit.
+func levenshtein(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > limit {
+		return limit + 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// maxTypos returns how many edits a query term of the given length may contain.
+func maxTypos(term string) int {
+	switch n := utf8.RuneCountInString(term); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func highlights(name, description string, match func(word string) bool) map[string]string {
+	result := make(map[string]string)
+	if fragment := highlight(name, match); fragment != "" {
+		result["name"] = fragment
+	}
+	if fragment := highlight(description, match); fragment != "" {
+		result["description"] = fragment
+	}
+
+	return result
+}
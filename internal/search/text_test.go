@@ -0,0 +1,85 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	matchAll := func(words ...string) func(string) bool {
+		return func(word string) bool {
+			for _, w := range words {
+				if word == w {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	tests := []struct {
+		name  string
+		text  string
+		match func(string) bool
+		want  string
+	}{
+		{"no match", "Red shoes", matchAll("blue"), ""},
+		{"single word", "Red shoes", matchAll("shoes"), "Red <em>shoes</em>"},
+		{"case insensitive", "RED Shoes", matchAll("red"), "<em>RED</em> Shoes"},
+		{"several words", "red shoes, red hat", matchAll("red"), "<em>red</em> shoes, <em>red</em> hat"},
+		{
+			"escapes markup",
+			`Lamp <img src=x onerror=alert(1)> & "shade"`,
+			matchAll("lamp"),
+			"<em>Lamp</em> &lt;img src=x onerror=alert(1)&gt; &amp; &#34;shade&#34;",
+		},
+		{
+			"escapes markup around matched words",
+			"<b>img</b>",
+			matchAll("img"),
+			"&lt;b&gt;<em>img</em>&lt;/b&gt;",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlight(tt.text, tt.match); got != tt.want {
+				t.Errorf("highlight(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightFragment(t *testing.T) {
+	text := strings.Repeat("filler ", 50) + "needle " + strings.Repeat("filler ", 50)
+	got := highlight(text, func(word string) bool { return word == "needle" })
+
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("fragment of a long text should be elided on both sides, got %q", got)
+	}
+	if !strings.Contains(got, "<em>needle</em>") {
+		t.Errorf("fragment should contain the match, got %q", got)
+	}
+	if n := len([]rune(strings.NewReplacer("<em>", "", "</em>", "", "…", "").Replace(got))); n > fragmentLength {
+		t.Errorf("fragment has %d runes, want at most %d", n, fragmentLength)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"shoes", "shoes", 2, 0},
+		{"shoes", "shoe", 2, 1},
+		{"shoes", "sheos", 2, 2},
+		{"kitten", "sitting", 3, 3},
+		{"kitten", "sitting", 1, 2},
+		{"café", "cafe", 1, 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b, tt.limit); got != tt.want {
+			t.Errorf("levenshtein(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.limit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/models"
+	"shop/internal/repositories"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AdminService struct {
+	userRepository         repositories.UserRepository
+	cartRepository         repositories.CartRepository
+	loginAttemptRepository repositories.LoginAttemptRepository
+}
+
+func (as *AdminService) GetUsers(query dto.UserListQuery, ctx context.Context) ([]models.User, int64, error) {
+	if query.Type != "" && !dto.UserType(query.Type).IsValid() {
+		return nil, 0, apperr.BadRequest("invalid user type")
+	}
+
+	filter := repositories.UserFilter{
+		Query:  query.Q,
+		Type:   query.Type,
+		Locked: query.Locked,
+		Limit:  query.PageSize,
+		Offset: query.Offset(),
+	}
+	users, total, err := as.userRepository.GetAll(filter, ctx)
+	if err != nil {
+		return nil, 0, apperr.Internal(err)
+	}
+
+	return users, total, nil
+}
+
+// ChangeUserType changes the user type. Users becoming customers get a cart if they
+// don't have one yet.
+func (as *AdminService) ChangeUserType(id uint, userType dto.UserType, admin *models.User, ctx context.Context) (*models.User, error) {
+	if !userType.IsValid() {
+		return nil, apperr.BadRequest("invalid user type")
+	}
+	if id == admin.ID {
+		return nil, apperr.BadRequest("you can't change your own type")
+	}
+
+	user, err := as.getUser(id, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := as.userRepository.UpdateType(user.ID, userType.String(), ctx); err != nil {
+		return nil, apperr.Internal(err)
+	}
+	user.Type = userType.String()
+
+	if userType == dto.TypeCustomer && user.Cart == nil {
+		cart := models.Cart{UserID: user.ID}
+		if err := as.cartRepository.Create(&cart, ctx); err != nil {
+			return nil, apperr.Internal(err)
+		}
+		user.Cart = &cart
+	}
+
+	return user, nil
+}
+
+func (as *AdminService) SetBanned(id uint, banned bool, admin *models.User, ctx context.Context) (*models.User, error) {
+	if id == admin.ID {
+		return nil, apperr.BadRequest("you can't ban yourself")
+	}
+
+	user, err := as.getUser(id, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var bannedAt *time.Time
+	if banned {
+		now := time.Now()
+		bannedAt = &now
+	}
+	if err := as.userRepository.UpdateBannedAt(user.ID, bannedAt, ctx); err != nil {
+		return nil, apperr.Internal(err)
+	}
+	user.BannedAt = bannedAt
+
+	return user, nil
+}
+
+// UnlockUser lifts the login lockout of the user and forgets the failed logins that
+// led to it.
+func (as *AdminService) UnlockUser(id uint, ctx context.Context) (*models.User, error) {
+	user, err := as.getUser(id, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := as.loginAttemptRepository.Unlock(user.Email, ctx); err != nil {
+		return nil, apperr.Internal(err)
+	}
+	user.LockedUntil = nil
+
+	return user, nil
+}
+
+func (as *AdminService) getUser(id uint, ctx context.Context) (*models.User, error) {
+	user, err := as.userRepository.FindByID(id, ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperr.NotFound("user not found")
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	return user, nil
+}
+
+func NewAdminService(
+	userRepository repositories.UserRepository,
+	cartRepository repositories.CartRepository,
+	loginAttemptRepository repositories.LoginAttemptRepository) *AdminService {
+
+	return &AdminService{
+		userRepository:         userRepository,
+		cartRepository:         cartRepository,
+		loginAttemptRepository: loginAttemptRepository,
+	}
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/models"
+	"shop/internal/repositories"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// APIKeyPrefix starts every API key, which tells them apart from access tokens.
+	APIKeyPrefix = "sk_"
+	// apiKeyVisibleLength is how many leading characters of a key are stored in plain
+	// text to identify it.
+	apiKeyVisibleLength = len(APIKeyPrefix) + 8
+	// apiKeyTouchInterval is how often the last use of a key is written at most.
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIKeyService manages the API keys sellers use to manage their catalog from their
+// own systems. A key acts on behalf of the seller who created it, but only on the
+// routes its scopes allow.
+type APIKeyService struct {
+	apiKeyRepository repositories.APIKeyRepository
+	userRepository   repositories.UserRepository
+}
+
+// Create issues a new key to the seller. The key is returned in plain text only here.
+func (as *APIKeyService) Create(user *models.User, req dto.CreateAPIKeyRequest, ctx context.Context) (*dto.CreatedAPIKeyResponse, error) {
+	var scopes []string
+	for _, scope := range req.Scopes {
+		if !dto.APIKeyScope(scope).IsValid() {
+			return nil, apperr.BadRequest("invalid scope: " + scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	secret, err := randomToken()
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	plain := APIKeyPrefix + secret
+	key := models.APIKey{
+		UserID:  user.ID,
+		Name:    req.Name,
+		Prefix:  plain[:apiKeyVisibleLength],
+		KeyHash: hashToken(plain),
+		Scopes:  strings.Join(scopes, " "),
+	}
+	if err := as.apiKeyRepository.Create(&key, ctx); err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	return &dto.CreatedAPIKeyResponse{APIKeyResponse: *dto.APIKeyToResp(&key), Key: plain}, nil
+}
+
+func (as *APIKeyService) GetUserKeys(user *models.User, ctx context.Context) ([]models.APIKey, error) {
+	keys, err := as.apiKeyRepository.GetByUserID(user.ID, ctx)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	return keys, nil
+}
+
+func (as *APIKeyService) Revoke(user *models.User, id uint, ctx context.Context) error {
+	err := as.apiKeyRepository.Revoke(id, user.ID, ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperr.NotFound("api key not found")
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	return nil
+}
+
+// Authenticate returns the seller the key belongs to if the key is active and has the
+// scope. Keys stop working when their owner is no longer a seller.
+func (as *APIKeyService) Authenticate(plain string, scope dto.APIKeyScope, ctx context.Context) (*models.User, error) {
+	invalidKey := apperr.Unauthorized("invalid api key").WithCode(apperr.CodeInvalidToken)
+
+	key, err := as.apiKeyRepository.FindByHash(hashToken(plain), ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, invalidKey
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if key.RevokedAt != nil {
+		return nil, apperr.Unauthorized("api key is revoked").WithCode(apperr.CodeInvalidToken)
+	}
+
+	user, err := as.userRepository.FindByID(key.UserID, ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, invalidKey
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if user.BannedAt != nil {
+		return nil, apperr.Forbidden("account is banned").WithCode(apperr.CodeAccountBanned)
+	}
+	if user.Type != dto.TypeSeller.String() {
+		return nil, invalidKey
+	}
+	if !slices.Contains(strings.Fields(key.Scopes), scope.String()) {
+		return nil, apperr.Forbidden("api key doesn't have the " + scope.String() + " scope")
+	}
+
+	if err := as.apiKeyRepository.TouchLastUsed(key, time.Now().Add(-apiKeyTouchInterval), ctx); err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	return user, nil
+}
+
+func NewAPIKeyService(apiKeyRepo repositories.APIKeyRepository, userRepo repositories.UserRepository) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepository: apiKeyRepo,
+		userRepository:   userRepo,
+	}
+}
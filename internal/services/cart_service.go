@@ -3,45 +3,91 @@ package services
 import (
 	"context"
 	"errors"
-	"net/http"
+	"fmt"
+	"shop/internal/apperr"
 	"shop/internal/dto"
 	"shop/internal/models"
 	"shop/internal/repositories"
+
+	"gorm.io/gorm"
 )
 
 type CartService struct {
 	cartRepository     repositories.CartRepository
 	cartItemRepository repositories.CartItemRepository
+	productRepository  repositories.ProductRepository
 }
 
-func (cs *CartService) ValidateUser(user *models.User) (int, error) {
+func (cs *CartService) ValidateUser(user *models.User) error {
 	if user.Type != string(dto.TypeCustomer) {
-		return http.StatusForbidden, errors.New("user doesn't have permissions")
+		return apperr.Forbidden("only customers have a cart")
 	}
 
-	return http.StatusOK, nil
+	return nil
 }
 
-func (cs *CartService) CheckItemBelongsToCart(id int, user *models.User, ctx context.Context) (int, error) {
-	existingItem, err := cs.cartItemRepository.GetItem(uint(id), ctx)
-	if err != nil {
-		return http.StatusInternalServerError, err
+func (cs *CartService) CheckItemBelongsToCart(id uint, user *models.User, ctx context.Context) (*models.CartItem, error) {
+	ctx, span := tracer.Start(ctx, "CartService.CheckItemBelongsToCart")
+	defer span.End()
+
+	existingItem, err := cs.cartItemRepository.GetItem(id, ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperr.NotFound("cart item not found")
 	}
-	if existingItem == nil {
-		return http.StatusNotFound, errors.New("not exist")
+	if err != nil {
+		return nil, apperr.Internal(err)
 	}
 	if user.Cart.ID != existingItem.CartID {
-		return http.StatusForbidden, errors.New("you are not allow to do this")
+		return nil, apperr.Forbidden("cart item belongs to another cart")
+	}
+
+	return existingItem, nil
+}
+
+// CheckStock verifies that the product has enough stock to hold the requested quantity
+// together with the quantity of the same product already in the cart. The cart item
+// with exceptItemID is not counted, so it can be used when the item quantity is replaced.
+func (cs *CartService) CheckStock(cartID, productID uint, quantity int, exceptItemID uint, ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "CartService.CheckStock")
+	defer span.End()
+
+	if quantity <= 0 {
+		return apperr.BadRequest("quantity must be greater than 0")
+	}
+
+	product, err := cs.productRepository.GetProduct(productID, ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperr.BadRequest("product not found")
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	cartItems, err := cs.cartItemRepository.GetAllByCartID(cartID, ctx)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	total := quantity
+	for _, item := range cartItems {
+		if item.ProductID == productID && item.ID != exceptItemID {
+			total += item.Quantity
+		}
+	}
+	if total > product.Stock {
+		return apperr.Conflict(fmt.Sprintf("only %d items of the product are in stock", product.Stock)).
+			WithCode(apperr.CodeInsufficientStock)
 	}
 
-	return http.StatusOK, nil
+	return nil
 }
 
 func NewCartService(
 	cartRepository repositories.CartRepository,
-	cartItemRepository repositories.CartItemRepository) *CartService {
+	cartItemRepository repositories.CartItemRepository,
+	productRepository repositories.ProductRepository) *CartService {
 	return &CartService{
 		cartRepository:     cartRepository,
 		cartItemRepository: cartItemRepository,
+		productRepository:  productRepository,
 	}
 }
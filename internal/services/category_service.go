@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/models"
+	"shop/internal/repositories"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+type CategoryService struct {
+	categoryRepository repositories.CategoryRepository
+}
+
+func (cs *CategoryService) GetCategory(id uint, ctx context.Context) (*models.Category, error) {
+	category, err := cs.categoryRepository.GetByID(id, ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperr.NotFound("category not found")
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	return category, nil
+}
+
+func (cs *CategoryService) CreateCategory(req dto.CreateUpdateCategoryRequest, ctx context.Context) (*models.Category, error) {
+	if req.ParentID != nil {
+		if err := cs.checkParentExists(*req.ParentID, ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	category := &models.Category{Name: req.Name, ParentID: req.ParentID}
+	err := cs.categoryRepository.Create(category, ctx)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, apperr.Conflict("category with this name already exists")
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	return category, nil
+}
+
+func (cs *CategoryService) UpdateCategory(id uint, req dto.CreateUpdateCategoryRequest, ctx context.Context) (*models.Category, error) {
+	category, err := cs.GetCategory(id, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ParentID != nil {
+		if err := cs.checkParentExists(*req.ParentID, ctx); err != nil {
+			return nil, err
+		}
+		descendantIDs, err := cs.categoryRepository.GetDescendantIDs(id, ctx)
+		if err != nil {
+			return nil, apperr.Internal(err)
+		}
+		if slices.Contains(descendantIDs, *req.ParentID) {
+			return nil, apperr.BadRequest("category can't be moved under itself or its subcategory")
+		}
+	}
+
+	category.Name = req.Name
+	category.ParentID = req.ParentID
+	err = cs.categoryRepository.Update(category, ctx)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, apperr.Conflict("category with this name already exists")
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	return category, nil
+}
+
+func (cs *CategoryService) DeleteCategory(id uint, ctx context.Context) error {
+	if _, err := cs.GetCategory(id, ctx); err != nil {
+		return err
+	}
+
+	children, err := cs.categoryRepository.CountChildren(id, ctx)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if children > 0 {
+		return apperr.Conflict("category has subcategories")
+	}
+	products, err := cs.categoryRepository.CountProducts(id, ctx)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if products > 0 {
+		return apperr.Conflict("category has products")
+	}
+
+	if err := cs.categoryRepository.Delete(id, ctx); err != nil {
+		return apperr.Internal(err)
+	}
+
+	return nil
+}
+
+func (cs *CategoryService) checkParentExists(parentID uint, ctx context.Context) error {
+	_, err := cs.categoryRepository.GetByID(parentID, ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperr.BadRequest("parent category not found")
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	return nil
+}
+
+func NewCategoryService(categoryRepository repositories.CategoryRepository) *CategoryService {
+	return &CategoryService{categoryRepository: categoryRepository}
+}
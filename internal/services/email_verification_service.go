@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shop/internal/apperr"
+	"shop/internal/mailer"
+	"shop/internal/models"
+	"shop/internal/repositories"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type EmailVerificationService struct {
+	verificationRepository repositories.EmailVerificationRepository
+	mailer                 mailer.Mailer
+	appURL                 string
+	tokenTTL               time.Duration
+	resendInterval         time.Duration
+}
+
+// SendVerification emails a verification link to the user.
+func (es *EmailVerificationService) SendVerification(user *models.User, ctx context.Context) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	err = es.verificationRepository.Create(&models.EmailVerificationToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(es.tokenTTL),
+	}, ctx)
+	if err != nil {
+		return err
+	}
+
+	return es.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf(
+			"Hello, %s!\n\nTo confirm your email, follow the link below. It is valid for %s.\n\n%s/api/v1/auth/verify?token=%s",
+			user.Username, es.tokenTTL, es.appURL, token,
+		),
+	}, ctx)
+}
+
+// Resend sends a new verification link. Links can not be requested more often than
+// once per resend interval.
+func (es *EmailVerificationService) Resend(user *models.User, ctx context.Context) error {
+	if user.EmailVerifiedAt != nil {
+		return apperr.Conflict("email is already verified")
+	}
+
+	latest, err := es.verificationRepository.FindLatestByUserID(user.ID, ctx)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperr.Internal(err)
+	}
+	if err == nil && time.Since(latest.CreatedAt) < es.resendInterval {
+		return apperr.TooManyRequests("verification email was sent recently, try again later")
+	}
+
+	if err := es.SendVerification(user, ctx); err != nil {
+		return apperr.Internal(err)
+	}
+
+	return nil
+}
+
+func (es *EmailVerificationService) Verify(token string, ctx context.Context) error {
+	invalidToken := apperr.BadRequest("invalid or expired verification token").WithCode(apperr.CodeInvalidToken)
+
+	verification, err := es.verificationRepository.FindByHash(hashToken(token), ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return invalidToken
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if time.Now().After(verification.ExpiresAt) {
+		return invalidToken
+	}
+
+	if err := es.verificationRepository.Verify(verification, ctx); err != nil {
+		return apperr.Internal(err)
+	}
+
+	return nil
+}
+
+func NewEmailVerificationService(
+	verificationRepo repositories.EmailVerificationRepository,
+	mailer mailer.Mailer,
+	appURL string,
+	tokenTTL time.Duration,
+	resendInterval time.Duration) *EmailVerificationService {
+
+	return &EmailVerificationService{
+		verificationRepository: verificationRepo,
+		mailer:                 mailer,
+		appURL:                 appURL,
+		tokenTTL:               tokenTTL,
+		resendInterval:         resendInterval,
+	}
+}
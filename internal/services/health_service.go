@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"shop/internal/dto"
+	"shop/internal/repositories"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type HealthService struct {
+	schemaRepository repositories.SchemaRepository
+	expectedVersion  uint
+	// ready reports whether the server accepts traffic; it turns false on shutdown.
+	ready  func() bool
+	logger *slog.Logger
+}
+
+// Readiness checks every dependency needed to serve requests. The status is 503
+// if any of them is unavailable.
+func (hs *HealthService) Readiness(ctx context.Context) (*dto.HealthResponse, int) {
+	checks := map[string]dto.DependencyCheck{
+		"server":     hs.checkServer(),
+		"database":   hs.checkDatabase(ctx),
+		"migrations": hs.checkMigrations(ctx),
+	}
+
+	resp := &dto.HealthResponse{Status: dto.HealthOK, Checks: checks}
+	for _, check := range checks {
+		if check.Status != dto.HealthOK {
+			resp.Status = dto.HealthUnavailable
+			return resp, http.StatusServiceUnavailable
+		}
+	}
+
+	return resp, http.StatusOK
+}
+
+func (hs *HealthService) checkServer() dto.DependencyCheck {
+	if !hs.ready() {
+		return dto.DependencyCheck{Status: dto.HealthUnavailable, Error: "server is shutting down"}
+	}
+
+	return dto.DependencyCheck{Status: dto.HealthOK}
+}
+
+func (hs *HealthService) checkDatabase(ctx context.Context) dto.DependencyCheck {
+	start := time.Now()
+	err := hs.schemaRepository.Ping(ctx)
+	check := dto.DependencyCheck{Status: dto.HealthOK, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		// Driver errors name hosts and users, they are only logged.
+		hs.logger.WarnContext(ctx, "Readiness check failed to ping the database", slog.Any("error", err))
+		check.Status = dto.HealthUnavailable
+		check.Error = "database is unreachable"
+	}
+
+	return check
+}
+
+func (hs *HealthService) checkMigrations(ctx context.Context) dto.DependencyCheck {
+	check := dto.DependencyCheck{Status: dto.HealthOK, ExpectedVersion: hs.expectedVersion}
+
+	version, dirty, err := hs.schemaRepository.GetVersion(ctx)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		check.Status = dto.HealthUnavailable
+		check.Error = "no migrations are applied"
+	case err != nil:
+		hs.logger.WarnContext(ctx, "Readiness check failed to read the schema version", slog.Any("error", err))
+		check.Status = dto.HealthUnavailable
+		check.Error = "schema version can't be read"
+	case dirty:
+		check.Status = dto.HealthUnavailable
+		check.Version = version
+		check.Error = fmt.Sprintf("migration %d failed and the schema is dirty", version)
+	case version != hs.expectedVersion:
+		check.Status = dto.HealthUnavailable
+		check.Version = version
+		check.Error = "schema version does not match the application"
+	default:
+		check.Version = version
+	}
+
+	return check
+}
+
+func NewHealthService(
+	schemaRepo repositories.SchemaRepository,
+	expectedVersion uint,
+	ready func() bool,
+	logger *slog.Logger) *HealthService {
+	return &HealthService{
+		schemaRepository: schemaRepo,
+		expectedVersion:  expectedVersion,
+		ready:            ready,
+		logger:           logger,
+	}
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/mailer"
+	"shop/internal/models"
+	"shop/internal/repositories"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LockoutPolicy configures the brute-force protection of logins.
+type LockoutPolicy struct {
+	// MaxFailures failed logins with an email within Window lock the email out for
+	// LockDuration.
+	MaxFailures  int
+	Window       time.Duration
+	LockDuration time.Duration
+	// MaxIPFailures failed logins from a client IP within Window block its further
+	// attempts, whatever email they use.
+	MaxIPFailures int
+	// After a failed login the next attempt with the email has to wait BaseDelay. The
+	// delay doubles with every further failure, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// LockoutService tracks failed logins per email and per client IP. Emails of unknown
+// accounts are tracked and locked out like any other, so the responses don't tell
+// whether an account exists.
+type LockoutService struct {
+	loginAttemptRepository  repositories.LoginAttemptRepository
+	accountUnlockRepository repositories.AccountUnlockRepository
+	mailer                  mailer.Mailer
+	appURL                  string
+	tokenTTL                time.Duration
+	policy                  LockoutPolicy
+	logger                  *slog.Logger
+}
+
+// Check refuses a login attempt while its client IP is blocked, its email is locked
+// out or the delay after the last failure with the email hasn't passed yet.
+func (ls *LockoutService) Check(email string, ip string, ctx context.Context) error {
+	now := time.Now()
+	tooManyFailures := apperr.TooManyRequests("too many failed login attempts, try again later")
+
+	ipFailures, err := ls.loginAttemptRepository.FailuresByIP(ip, now.Add(-ls.policy.Window), ctx)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if ipFailures.Count > 0 && ipFailures.Count >= int64(ls.policy.MaxIPFailures) {
+		return tooManyFailures.WithRetryAfter(ipFailures.FirstAt.Add(ls.policy.Window).Sub(now))
+	}
+
+	lockout, err := ls.loginAttemptRepository.FindLockout(email, ctx)
+	if err == nil {
+		return apperr.TooManyRequests("account is temporarily locked after too many failed login attempts").
+			WithCode(apperr.CodeAccountLocked).
+			WithRetryAfter(lockout.LockedUntil.Sub(now))
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperr.Internal(err)
+	}
+
+	failures, err := ls.loginAttemptRepository.FailuresByEmail(email, now.Add(-ls.policy.Window), ctx)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if failures.Count > 0 {
+		if wait := failures.LastAt.Add(ls.delay(failures.Count)).Sub(now); wait > 0 {
+			return tooManyFailures.WithRetryAfter(wait)
+		}
+	}
+
+	return nil
+}
+
+// RecordFailure records a failed login with the email and locks the email out once it
+// has failed too often. user is the account with the email, or nil if there is none.
+// The owner of the account is emailed a link to lift the lockout early.
+func (ls *LockoutService) RecordFailure(email string, ip string, user *models.User, ctx context.Context) error {
+	since := time.Now().Add(-ls.policy.Window)
+	err := ls.loginAttemptRepository.RecordFailure(&models.LoginFailure{Email: email, IP: ip}, since, ctx)
+	if err != nil {
+		return err
+	}
+
+	failures, err := ls.loginAttemptRepository.FailuresByEmail(email, since, ctx)
+	if err != nil {
+		return err
+	}
+	if failures.Count < int64(ls.policy.MaxFailures) {
+		return nil
+	}
+
+	lockout := models.LoginLockout{Email: email, LockedUntil: time.Now().Add(ls.policy.LockDuration)}
+	if err := ls.loginAttemptRepository.Lock(&lockout, ctx); err != nil {
+		return err
+	}
+	ls.logger.WarnContext(ctx, "Login locked out after too many failures", slog.String("email", email), slog.String("ip", ip))
+
+	if user != nil {
+		// The email is sent after the response, so it must not be cancelled with the request.
+		go func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+			defer cancel()
+			if err := ls.sendUnlockLink(user, ctx); err != nil {
+				ls.logger.ErrorContext(ctx, "Failed to send unlock link", slog.Uint64("user_id", uint64(user.ID)), slog.Any("error", err))
+			}
+		}()
+	}
+
+	return nil
+}
+
+// RecordSuccess forgets the failed logins with the email.
+func (ls *LockoutService) RecordSuccess(email string, ctx context.Context) error {
+	return ls.loginAttemptRepository.ClearFailures(email, ctx)
+}
+
+// Unlock lifts the lockout of an account using a token from an unlock link.
+func (ls *LockoutService) Unlock(req dto.UnlockAccountRequest, ctx context.Context) error {
+	invalidToken := apperr.BadRequest("invalid or expired unlock token").WithCode(apperr.CodeInvalidToken)
+
+	token, err := ls.accountUnlockRepository.FindByHash(hashToken(req.Token), ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return invalidToken
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return invalidToken
+	}
+
+	err = ls.accountUnlockRepository.Unlock(token, ctx)
+	if errors.Is(err, repositories.ErrUnlockTokenUsed) {
+		return invalidToken
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	return nil
+}
+
+// delay is how long the next attempt has to wait after the given number of failures.
+func (ls *LockoutService) delay(failures int64) time.Duration {
+	delay := ls.policy.BaseDelay
+	for i := int64(1); i < failures && delay < ls.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, ls.policy.MaxDelay)
+}
+
+func (ls *LockoutService) sendUnlockLink(user *models.User, ctx context.Context) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	err = ls.accountUnlockRepository.Create(&models.AccountUnlockToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ls.tokenTTL),
+	}, ctx)
+	if err != nil {
+		return err
+	}
+
+	return ls.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your account has been locked",
+		Body: fmt.Sprintf(
+			"Hello, %s!\n\nWe have locked your account for %s after too many failed login attempts. If it was you, follow the link below to unlock it now. The link is valid for %s.\n\n%s/unlock-account?token=%s\n\nIf it was not you, consider changing your password.",
+			user.Username, ls.policy.LockDuration, ls.tokenTTL, ls.appURL, token,
+		),
+	}, ctx)
+}
+
+func NewLockoutService(
+	loginAttemptRepo repositories.LoginAttemptRepository,
+	accountUnlockRepo repositories.AccountUnlockRepository,
+	mailer mailer.Mailer,
+	appURL string,
+	tokenTTL time.Duration,
+	policy LockoutPolicy,
+	logger *slog.Logger) *LockoutService {
+
+	return &LockoutService{
+		loginAttemptRepository:  loginAttemptRepo,
+		accountUnlockRepository: accountUnlockRepo,
+		mailer:                  mailer,
+		appURL:                  appURL,
+		tokenTTL:                tokenTTL,
+		policy:                  policy,
+		logger:                  logger,
+	}
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/metrics"
+	"shop/internal/models"
+	"shop/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// orderTransitions lists the statuses an order may move to from each status.
+var orderTransitions = map[dto.OrderStatus][]dto.OrderStatus{
+	dto.StatusPending:   {dto.StatusPaid, dto.StatusCancelled},
+	dto.StatusPaid:      {dto.StatusShipped, dto.StatusCancelled, dto.StatusRefunded},
+	dto.StatusShipped:   {dto.StatusDelivered},
+	dto.StatusDelivered: {dto.StatusRefunded},
+	dto.StatusCancelled: {},
+	dto.StatusRefunded:  {},
+}
+
+// sellerTransitions are the fulfilment steps sellers may take. Payment, cancellation
+// and refunds concern the whole order and are left to administrators.
+var sellerTransitions = map[dto.OrderStatus][]dto.OrderStatus{
+	dto.StatusPaid:    {dto.StatusShipped},
+	dto.StatusShipped: {dto.StatusDelivered},
+}
+
+func CanTransition(from, to dto.OrderStatus) bool {
+	return allowed(orderTransitions, from, to)
+}
+
+func allowed(transitions map[dto.OrderStatus][]dto.OrderStatus, from, to dto.OrderStatus) bool {
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+type OrderService struct {
+	orderRepository repositories.OrderRepository
+	metrics         *metrics.Metrics
+}
+
+func (ors *OrderService) Checkout(user *models.User, ctx context.Context) (*models.Order, error) {
+	if user.Cart == nil {
+		return nil, apperr.Forbidden("user doesn't have a cart")
+	}
+
+	order := &models.Order{
+		UserID: user.ID,
+		Status: dto.StatusPending.String(),
+	}
+	err := ors.orderRepository.CreateFromCart(order, user.Cart.ID, ctx)
+	if errors.Is(err, repositories.ErrCartEmpty) {
+		return nil, apperr.BadRequest(err.Error()).WithCode(apperr.CodeCartEmpty)
+	}
+	if errors.Is(err, repositories.ErrInsufficientStock) {
+		return nil, apperr.Conflict(err.Error()).WithCode(apperr.CodeInsufficientStock)
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	ors.metrics.OrderPlaced(order.TotalPrice)
+
+	return order, nil
+}
+
+func (ors *OrderService) GetUserOrders(user *models.User, query dto.PaginationQuery, ctx context.Context) ([]models.Order, int64, error) {
+	filter := repositories.OrderFilter{UserID: user.ID, Limit: query.PageSize, Offset: query.Offset()}
+	orders, total, err := ors.orderRepository.GetAll(filter, ctx)
+	if err != nil {
+		return nil, 0, apperr.Internal(err)
+	}
+
+	return orders, total, nil
+}
+
+// GetSellerOrders returns the orders that contain products of the seller, each with
+// only the items of the seller and their total.
+func (ors *OrderService) GetSellerOrders(seller *models.User, query dto.SellerOrderListQuery, ctx context.Context) ([]models.Order, int64, error) {
+	if query.Status != "" && !dto.OrderStatus(query.Status).IsValid() {
+		return nil, 0, apperr.BadRequest("invalid order status")
+	}
+
+	filter := repositories.OrderFilter{
+		SellerID: seller.ID,
+		Status:   query.Status,
+		Limit:    query.PageSize,
+		Offset:   query.Offset(),
+	}
+	orders, total, err := ors.orderRepository.GetAll(filter, ctx)
+	if err != nil {
+		return nil, 0, apperr.Internal(err)
+	}
+	// The repository already left only the items of the seller.
+	for i := range orders {
+		orders[i].TotalPrice = itemsTotal(orders[i].Items)
+	}
+
+	return orders, total, nil
+}
+
+func (ors *OrderService) GetAllOrders(query dto.AdminOrderListQuery, ctx context.Context) ([]models.Order, int64, error) {
+	if query.Status != "" && !dto.OrderStatus(query.Status).IsValid() {
+		return nil, 0, apperr.BadRequest("invalid order status")
+	}
+
+	filter := repositories.OrderFilter{
+		UserID: query.UserID,
+		Status: query.Status,
+		Limit:  query.PageSize,
+		Offset: query.Offset(),
+	}
+	orders, total, err := ors.orderRepository.GetAll(filter, ctx)
+	if err != nil {
+		return nil, 0, apperr.Internal(err)
+	}
+
+	return orders, total, nil
+}
+
+// GetOrderIfOwned returns the order to its customer and administrators. Sellers get
+// orders that contain their products, with only their items.
+func (ors *OrderService) GetOrderIfOwned(id uint, user *models.User, ctx context.Context) (*models.Order, error) {
+	order, err := ors.orderRepository.GetByID(id, ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperr.NotFound("order not found")
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if order.UserID == user.ID || user.Type == string(dto.TypeAdministrator) {
+		return order, nil
+	}
+	if user.Type == string(dto.TypeSeller) && sellsIn(order, user.ID) {
+		return sellerView(order, user.ID), nil
+	}
+
+	return nil, apperr.Forbidden("you are not allowed to view this order")
+}
+
+func (ors *OrderService) UpdateStatus(id uint, status dto.OrderStatus, user *models.User, ctx context.Context) (*models.Order, error) {
+	if !status.IsValid() {
+		return nil, apperr.BadRequest("invalid order status")
+	}
+
+	order, err := ors.orderRepository.GetByID(id, ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperr.NotFound("order not found")
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if !canManageOrder(order, user) {
+		return nil, apperr.Forbidden("you are not allowed to change this order")
+	}
+
+	from := dto.OrderStatus(order.Status)
+	if !CanTransition(from, status) {
+		return nil, apperr.Conflict(fmt.Sprintf("order can't be moved from %s to %s", from, status)).
+			WithCode(apperr.CodeInvalidTransition)
+	}
+	isSeller := user.Type == string(dto.TypeSeller)
+	if isSeller && !allowed(sellerTransitions, from, status) {
+		return nil, apperr.Forbidden("sellers can only mark paid orders as shipped and shipped orders as delivered")
+	}
+	if isSeller && !sellsAll(order, user.ID) {
+		return nil, apperr.Forbidden("order contains products of other sellers, only an administrator can change its status")
+	}
+
+	restock := status == dto.StatusCancelled
+	err = ors.orderRepository.UpdateStatus(order, status.String(), user.ID, restock, ctx)
+	if errors.Is(err, repositories.ErrOrderStatusChanged) {
+		return nil, apperr.Conflict(err.Error())
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if isSeller {
+		return sellerView(order, user.ID), nil
+	}
+
+	return order, nil
+}
+
+// canManageOrder reports whether the user may change the order status: administrators can
+// manage any order, sellers only the fulfilment of orders that contain their products.
+func canManageOrder(order *models.Order, user *models.User) bool {
+	switch user.Type {
+	case string(dto.TypeAdministrator):
+		return true
+	case string(dto.TypeSeller):
+		return sellsIn(order, user.ID)
+	}
+
+	return false
+}
+
+// sellsIn reports whether the order contains products of the seller.
+func sellsIn(order *models.Order, sellerID uint) bool {
+	for _, item := range order.Items {
+		if item.Product.UserID == sellerID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sellsAll reports whether every item of the order is a product of the seller. The status
+// covers the whole order, so sellers may only change it when nobody else has to ship.
+func sellsAll(order *models.Order, sellerID uint) bool {
+	for _, item := range order.Items {
+		if item.Product.UserID != sellerID {
+			return false
+		}
+	}
+
+	return len(order.Items) > 0
+}
+
+// sellerView leaves only the items of the seller in the order, and their total in place
+// of the order total, so sellers don't see what customers bought from others.
+func sellerView(order *models.Order, sellerID uint) *models.Order {
+	items := make([]models.OrderItem, 0, len(order.Items))
+	for _, item := range order.Items {
+		if item.Product.UserID == sellerID {
+			items = append(items, item)
+		}
+	}
+	order.Items = items
+	order.TotalPrice = itemsTotal(items)
+
+	return order
+}
+
+// itemsTotal sums up the price of the items the way checkout computes the order total.
+func itemsTotal(items []models.OrderItem) float64 {
+	var total float64
+	for _, item := range items {
+		total += item.Price * float64(item.Quantity)
+	}
+
+	return math.Round(total*100) / 100
+}
+
+func NewOrderService(orderRepository repositories.OrderRepository, metrics *metrics.Metrics) *OrderService {
+	return &OrderService{orderRepository: orderRepository, metrics: metrics}
+}
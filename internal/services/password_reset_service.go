@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/mailer"
+	"shop/internal/models"
+	"shop/internal/repositories"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+type PasswordResetService struct {
+	userRepository          repositories.UserRepository
+	passwordResetRepository repositories.PasswordResetRepository
+	mailer                  mailer.Mailer
+	appURL                  string
+	tokenTTL                time.Duration
+	logger                  *slog.Logger
+}
+
+// RequestReset emails a password reset link to the user with the given email. The
+// work is done in the background so neither the response nor its timing tells
+// whether the email is registered.
+func (ps *PasswordResetService) RequestReset(req dto.ForgotPasswordRequest, ctx context.Context) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		if err := ps.sendResetLink(req.Email, ctx); err != nil {
+			ps.logger.ErrorContext(ctx, "Failed to send password reset link", slog.Any("error", err))
+		}
+	}()
+}
+
+// ResetPassword sets a new password using a token from a reset link. Every session of
+// the user is revoked afterwards.
+func (ps *PasswordResetService) ResetPassword(req dto.ResetPasswordRequest, ctx context.Context) error {
+	invalidToken := apperr.BadRequest("invalid or expired reset token").WithCode(apperr.CodeInvalidToken)
+
+	token, err := ps.passwordResetRepository.FindByHash(hashToken(req.Token), ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return invalidToken
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return invalidToken
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	err = ps.passwordResetRepository.ResetPassword(token, string(hashedPassword), ctx)
+	if errors.Is(err, repositories.ErrResetTokenUsed) {
+		return invalidToken
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	return nil
+}
+
+func (ps *PasswordResetService) sendResetLink(email string, ctx context.Context) error {
+	user, err := ps.userRepository.FindByEmail(email, ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	err = ps.passwordResetRepository.Create(&models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ps.tokenTTL),
+	}, ctx)
+	if err != nil {
+		return err
+	}
+
+	return ps.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf(
+			"Hello, %s!\n\nTo set a new password, follow the link below. It is valid for %s.\n\n%s/reset-password?token=%s\n\nIf you did not request a password reset, ignore this email.",
+			user.Username, ps.tokenTTL, ps.appURL, token,
+		),
+	}, ctx)
+}
+
+func NewPasswordResetService(
+	userRepo repositories.UserRepository,
+	passwordResetRepo repositories.PasswordResetRepository,
+	mailer mailer.Mailer,
+	appURL string,
+	tokenTTL time.Duration,
+	logger *slog.Logger) *PasswordResetService {
+
+	return &PasswordResetService{
+		userRepository:          userRepo,
+		passwordResetRepository: passwordResetRepo,
+		mailer:                  mailer,
+		appURL:                  appURL,
+		tokenTTL:                tokenTTL,
+		logger:                  logger,
+	}
+}
@@ -3,37 +3,220 @@ package services
 import (
 	"context"
 	"errors"
-	"net/http"
+	"log/slog"
+	"shop/internal/apperr"
 	"shop/internal/dto"
 	"shop/internal/models"
 	"shop/internal/repositories"
+	"shop/internal/search"
 
 	"gorm.io/gorm"
 )
 
 type ProductService struct {
-	productRepository repositories.ProductRepository
+	productRepository  repositories.ProductRepository
+	categoryRepository repositories.CategoryRepository
+	searchIndex        search.SearchIndex
+	logger             *slog.Logger
 }
 
-func (ps *ProductService) GetProductIfAuthorized(id uint, user *models.User, ctx context.Context) (*models.Product, int, error) {
+// GetProducts returns a page of products matching the query. Filtering by category
+// includes the products of all its subcategories.
+func (ps *ProductService) GetProducts(query dto.ProductListQuery, ctx context.Context) ([]models.Product, int64, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetProducts")
+	defer span.End()
+
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		return nil, 0, apperr.BadRequest("min_price can't be greater than max_price")
+	}
+
+	filter := repositories.ProductFilter{
+		SellerID: query.SellerID,
+		MinPrice: query.MinPrice,
+		MaxPrice: query.MaxPrice,
+		SortBy:   query.Sort,
+		Desc:     query.Direction == "desc" || (query.Direction == "" && query.Sort == ""),
+		Limit:    query.PageSize,
+		Offset:   query.Offset(),
+	}
+	if query.CategoryID != 0 {
+		categoryIDs, err := ps.categoryRepository.GetDescendantIDs(query.CategoryID, ctx)
+		if err != nil {
+			return nil, 0, apperr.Internal(err)
+		}
+		if len(categoryIDs) == 0 {
+			return nil, 0, apperr.NotFound("category not found")
+		}
+		filter.CategoryIDs = categoryIDs
+	}
+
+	products, total, err := ps.productRepository.GetAll(filter, ctx)
+	if err != nil {
+		return nil, 0, apperr.Internal(err)
+	}
+
+	return products, total, nil
+}
+
+func (ps *ProductService) GetProduct(id uint, ctx context.Context) (*models.Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetProduct")
+	defer span.End()
+
 	product, err := ps.productRepository.GetProduct(id, ctx)
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, http.StatusNotFound, errors.New("product not found")
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && product == nil) {
+		return nil, apperr.NotFound("product not found")
 	}
 	if err != nil {
-		return nil, http.StatusInternalServerError, errors.New("failed to retrieve product")
+		return nil, apperr.Internal(err)
 	}
-	if product == nil {
-		return nil, http.StatusNotFound, errors.New("product not found")
+
+	return product, nil
+}
+
+func (ps *ProductService) GetProductIfAuthorized(id uint, user *models.User, ctx context.Context) (*models.Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetProductIfAuthorized")
+	defer span.End()
+
+	product, err := ps.GetProduct(id, ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	if user.ID != product.UserID || user.Type != string(dto.TypeSeller) {
-		return nil, http.StatusForbidden, errors.New("you are not allowed to modify this product")
+		return nil, apperr.Forbidden("you are not allowed to modify this product")
 	}
 
-	return product, http.StatusOK, nil
+	return product, nil
 }
 
-func NewProductService(productRepository repositories.ProductRepository) *ProductService {
-	return &ProductService{productRepository: productRepository}
+// GetProductIfDeletable returns the product if the user may delete it: its seller or an
+// administrator, who can take down any listing but not edit it.
+func (ps *ProductService) GetProductIfDeletable(id uint, user *models.User, ctx context.Context) (*models.Product, error) {
+	if user.Type != string(dto.TypeAdministrator) {
+		return ps.GetProductIfAuthorized(id, user, ctx)
+	}
+
+	return ps.GetProduct(id, ctx)
+}
+
+// SearchProducts returns a page of products matching the text query, most relevant first.
+func (ps *ProductService) SearchProducts(query dto.ProductSearchQuery, ctx context.Context) ([]dto.ProductSearchHit, int64, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.SearchProducts")
+	defer span.End()
+
+	result, err := ps.searchIndex.Search(query.Q, query.PageSize, query.Offset(), ctx)
+	if err != nil {
+		return nil, 0, apperr.Internal(err)
+	}
+	if len(result.Hits) == 0 {
+		return []dto.ProductSearchHit{}, result.Total, nil
+	}
+
+	ids := make([]uint, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		ids = append(ids, hit.ProductID)
+	}
+	products, err := ps.productRepository.GetByIDs(ids, ctx)
+	if err != nil {
+		return nil, 0, apperr.Internal(err)
+	}
+	productsByID := make(map[uint]*models.Product, len(products))
+	for i := range products {
+		productsByID[products[i].ID] = &products[i]
+	}
+
+	hits := make([]dto.ProductSearchHit, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		product, ok := productsByID[hit.ProductID]
+		if !ok {
+			continue
+		}
+		hits = append(hits, dto.ProductSearchHit{
+			ProductResponse: *dto.ProductToResp(product),
+			Score:           hit.Score,
+			Highlights:      hit.Highlights,
+		})
+	}
+
+	return hits, result.Total, nil
+}
+
+// UpdateStock sets or adjusts the stock of the product as requested and stores the new
+// stock in product.
+func (ps *ProductService) UpdateStock(product *models.Product, req dto.UpdateStockRequest, ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "ProductService.UpdateStock")
+	defer span.End()
+
+	stock := 0
+	var err error
+	if req.Adjustment != nil {
+		stock, err = ps.productRepository.AdjustStock(product.ID, *req.Adjustment, ctx)
+	} else {
+		stock = *req.Stock
+		err = ps.productRepository.UpdateStock(product.ID, stock, req.ExpectedStock, ctx)
+	}
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return apperr.NotFound("product not found")
+	case errors.Is(err, repositories.ErrStockChanged):
+		return apperr.Conflict("stock has changed since it was read, read it again and retry")
+	case errors.Is(err, repositories.ErrInsufficientStock):
+		return apperr.Conflict("adjustment would take the stock below zero").WithCode(apperr.CodeInsufficientStock)
+	case err != nil:
+		return apperr.Internal(err)
+	}
+	product.Stock = stock
+
+	return nil
+}
+
+// DeleteProduct deletes the product and removes it from the search index. Products that
+// have been ordered stay referenced by the orders and can't be deleted.
+func (ps *ProductService) DeleteProduct(id uint, ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "ProductService.DeleteProduct")
+	defer span.End()
+
+	err := ps.productRepository.DeleteProduct(id, ctx)
+	if errors.Is(err, gorm.ErrForeignKeyViolated) {
+		return apperr.Conflict("product has been ordered and can't be deleted, set its stock to 0 to stop selling it")
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	ps.RemoveFromIndex(id, ctx)
+
+	return nil
+}
+
+// IndexProduct adds the product to the search index or refreshes it there. A failure
+// is only logged: the product itself is already saved.
+func (ps *ProductService) IndexProduct(product *models.Product, ctx context.Context) {
+	ctx, span := tracer.Start(ctx, "ProductService.IndexProduct")
+	defer span.End()
+
+	if err := ps.searchIndex.Index(product, ctx); err != nil {
+		ps.logger.ErrorContext(ctx, "Failed to index product", slog.Uint64("product_id", uint64(product.ID)), slog.Any("error", err))
+	}
+}
+
+func (ps *ProductService) RemoveFromIndex(productID uint, ctx context.Context) {
+	ctx, span := tracer.Start(ctx, "ProductService.RemoveFromIndex")
+	defer span.End()
+
+	if err := ps.searchIndex.Remove(productID, ctx); err != nil {
+		ps.logger.ErrorContext(ctx, "Failed to remove product from search index", slog.Uint64("product_id", uint64(productID)), slog.Any("error", err))
+	}
+}
+
+func NewProductService(
+	productRepository repositories.ProductRepository,
+	categoryRepository repositories.CategoryRepository,
+	searchIndex search.SearchIndex,
+	logger *slog.Logger) *ProductService {
+	return &ProductService{
+		productRepository:  productRepository,
+		categoryRepository: categoryRepository,
+		searchIndex:        searchIndex,
+		logger:             logger,
+	}
 }
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/models"
+	"shop/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+type SellerApplicationService struct {
+	applicationRepository repositories.SellerApplicationRepository
+}
+
+func (ss *SellerApplicationService) Apply(user *models.User, req dto.SellerApplicationRequest, ctx context.Context) (*models.SellerApplication, error) {
+	if user.Type != string(dto.TypeCustomer) {
+		return nil, apperr.Forbidden("only customers can apply to become sellers")
+	}
+
+	filter := repositories.SellerApplicationFilter{UserID: user.ID, Status: dto.ApplicationPending.String(), Limit: 1}
+	_, pending, err := ss.applicationRepository.GetAll(filter, ctx)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if pending > 0 {
+		return nil, apperr.Conflict("you already have a pending application")
+	}
+
+	application := &models.SellerApplication{
+		UserID:  user.ID,
+		Message: req.Message,
+		Status:  dto.ApplicationPending.String(),
+	}
+	if err := ss.applicationRepository.Create(application, ctx); err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	return application, nil
+}
+
+func (ss *SellerApplicationService) GetApplications(userID uint, query dto.SellerApplicationListQuery, ctx context.Context) ([]models.SellerApplication, int64, error) {
+	if query.Status != "" && !dto.SellerApplicationStatus(query.Status).IsValid() {
+		return nil, 0, apperr.BadRequest("invalid application status")
+	}
+
+	filter := repositories.SellerApplicationFilter{
+		UserID: userID,
+		Status: query.Status,
+		Limit:  query.PageSize,
+		Offset: query.Offset(),
+	}
+	applications, total, err := ss.applicationRepository.GetAll(filter, ctx)
+	if err != nil {
+		return nil, 0, apperr.Internal(err)
+	}
+
+	return applications, total, nil
+}
+
+// Review approves or rejects a pending application. Approval turns the applicant into a seller.
+func (ss *SellerApplicationService) Review(id uint, approve bool, admin *models.User, ctx context.Context) (*models.SellerApplication, error) {
+	application, err := ss.applicationRepository.GetByID(id, ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperr.NotFound("application not found")
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if application.Status != dto.ApplicationPending.String() {
+		return nil, apperr.Conflict(repositories.ErrApplicationReviewed.Error())
+	}
+
+	status, userType := dto.ApplicationRejected, ""
+	if approve {
+		status, userType = dto.ApplicationApproved, dto.TypeSeller.String()
+	}
+	err = ss.applicationRepository.Review(application, status.String(), admin.ID, userType, ctx)
+	if errors.Is(err, repositories.ErrApplicationReviewed) {
+		return nil, apperr.Conflict(err.Error())
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	return application, nil
+}
+
+func NewSellerApplicationService(applicationRepository repositories.SellerApplicationRepository) *SellerApplicationService {
+	return &SellerApplicationService{applicationRepository: applicationRepository}
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/jwtkeys"
+	"shop/internal/models"
+	"shop/internal/repositories"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// AccessClaims are the claims of an access token.
+type AccessClaims struct {
+	UserID       uint `json:"userId"`
+	TokenVersion uint `json:"ver"`
+	jwt.RegisteredClaims
+}
+
+// TokenService issues short-lived access tokens together with long-lived refresh tokens.
+// Refresh tokens are stored hashed and rotated on every use; all tokens obtained from one
+// login form a family, and presenting an already rotated token revokes the whole family.
+type TokenService struct {
+	tokenRepository repositories.TokenRepository
+	userRepository  repositories.UserRepository
+	keys            *jwtkeys.KeySet
+	accessTTL       time.Duration
+	refreshTTL      time.Duration
+}
+
+// IssueTokens starts a new token family for the user.
+func (ts *TokenService) IssueTokens(user *models.User, ctx context.Context) (*dto.TokenResponse, error) {
+	familyID, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, record, err := ts.newRefreshToken(user.ID, familyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ts.tokenRepository.CreateRefreshToken(record, ctx); err != nil {
+		return nil, err
+	}
+
+	return ts.tokenResponse(user, refreshToken)
+}
+
+// Refresh exchanges a refresh token for a new token pair.
+func (ts *TokenService) Refresh(refreshToken string, ctx context.Context) (*dto.TokenResponse, error) {
+	invalidToken := apperr.Unauthorized("invalid refresh token").WithCode(apperr.CodeInvalidToken)
+
+	record, err := ts.tokenRepository.FindRefreshTokenByHash(hashToken(refreshToken), ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, invalidToken
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if record.RevokedAt != nil {
+		return nil, ts.revokeReusedFamily(record, ctx)
+	}
+	if time.Now().After(record.ExpiresAt) {
+		return nil, apperr.Unauthorized("refresh token is expired").WithCode(apperr.CodeInvalidToken)
+	}
+
+	user, err := ts.userRepository.FindByID(record.UserID, ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, invalidToken
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if user.BannedAt != nil {
+		return nil, apperr.Forbidden("account is banned").WithCode(apperr.CodeAccountBanned)
+	}
+
+	nextToken, next, err := ts.newRefreshToken(user.ID, record.FamilyID)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	err = ts.tokenRepository.RotateRefreshToken(record, next, ctx)
+	if errors.Is(err, repositories.ErrRefreshTokenRevoked) {
+		return nil, ts.revokeReusedFamily(record, ctx)
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	resp, err := ts.tokenResponse(user, nextToken)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	return resp, nil
+}
+
+// Logout revokes the access token described by claims and, if given, the family of
+// the refresh token. Refresh tokens of other users are ignored.
+func (ts *TokenService) Logout(user *models.User, claims *AccessClaims, refreshToken string, ctx context.Context) error {
+	if refreshToken != "" {
+		record, err := ts.tokenRepository.FindRefreshTokenByHash(hashToken(refreshToken), ctx)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.Internal(err)
+		}
+		if err == nil && record.UserID == user.ID {
+			if err := ts.tokenRepository.RevokeRefreshTokenFamily(record.FamilyID, ctx); err != nil {
+				return apperr.Internal(err)
+			}
+		}
+	}
+
+	if claims != nil && claims.ExpiresAt != nil {
+		if err := ts.tokenRepository.RevokeAccessToken(claims.ID, claims.ExpiresAt.Time, ctx); err != nil {
+			return apperr.Internal(err)
+		}
+	}
+
+	return nil
+}
+
+// LogoutAll revokes every token of the user on every device.
+func (ts *TokenService) LogoutAll(user *models.User, ctx context.Context) error {
+	if err := ts.tokenRepository.RevokeAllUserTokens(user.ID, ctx); err != nil {
+		return apperr.Internal(err)
+	}
+
+	return nil
+}
+
+// ParseAccessToken validates the signature and expiry of an access token.
+func (ts *TokenService) ParseAccessToken(tokenString string) (*AccessClaims, error) {
+	var claims AccessClaims
+	token, err := jwt.ParseWithClaims(tokenString, &claims, ts.keys.Keyfunc,
+		jwt.WithValidMethods(ts.keys.Algorithms()), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	if claims.ID == "" || claims.UserID == 0 {
+		return nil, errors.New("invalid token")
+	}
+
+	return &claims, nil
+}
+
+// IsRevoked reports whether the access token can no longer be used even though it
+// has not expired yet.
+func (ts *TokenService) IsRevoked(claims *AccessClaims, user *models.User, ctx context.Context) (bool, error) {
+	if claims.TokenVersion != user.TokenVersion {
+		return true, nil
+	}
+
+	return ts.tokenRepository.IsAccessTokenRevoked(claims.ID, ctx)
+}
+
+func (ts *TokenService) revokeReusedFamily(record *models.RefreshToken, ctx context.Context) error {
+	if err := ts.tokenRepository.RevokeRefreshTokenFamily(record.FamilyID, ctx); err != nil {
+		return apperr.Internal(err)
+	}
+
+	return apperr.Unauthorized("refresh token is revoked").WithCode(apperr.CodeInvalidToken)
+}
+
+func (ts *TokenService) newRefreshToken(userID uint, familyID string) (string, *models.RefreshToken, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, &models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ts.refreshTTL),
+	}, nil
+}
+
+func (ts *TokenService) tokenResponse(user *models.User, refreshToken string) (*dto.TokenResponse, error) {
+	jti, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	accessToken, err := ts.keys.Sign(jwt.NewWithClaims(jwt.SigningMethodNone, AccessClaims{
+		UserID:       user.ID,
+		TokenVersion: user.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ts.accessTTL)),
+		},
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(ts.accessTTL.Seconds()),
+	}, nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewTokenService(
+	tokenRepo repositories.TokenRepository,
+	userRepo repositories.UserRepository,
+	keys *jwtkeys.KeySet,
+	accessTTL time.Duration,
+	refreshTTL time.Duration) *TokenService {
+
+	return &TokenService{
+		tokenRepository: tokenRepo,
+		userRepository:  userRepo,
+		keys:            keys,
+		accessTTL:       accessTTL,
+		refreshTTL:      refreshTTL,
+	}
+}
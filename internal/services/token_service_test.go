@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"shop/internal/apperr"
+	"shop/internal/jwtkeys"
+	"shop/internal/models"
+	"shop/internal/repositories"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakeTokenRepository keeps refresh tokens in memory, keyed by their hash.
+type fakeTokenRepository struct {
+	repositories.TokenRepository
+	users   *fakeUserRepository
+	tokens  map[string]*models.RefreshToken
+	revoked map[string]bool
+	nextID  uint
+}
+
+func newFakeTokenRepository(users *fakeUserRepository) *fakeTokenRepository {
+	return &fakeTokenRepository{
+		users:   users,
+		tokens:  map[string]*models.RefreshToken{},
+		revoked: map[string]bool{},
+	}
+}
+
+func (f *fakeTokenRepository) CreateRefreshToken(token *models.RefreshToken, ctx context.Context) error {
+	f.nextID++
+	token.ID = f.nextID
+	stored := *token
+	f.tokens[token.TokenHash] = &stored
+	return nil
+}
+
+func (f *fakeTokenRepository) FindRefreshTokenByHash(hash string, ctx context.Context) (*models.RefreshToken, error) {
+	token, ok := f.tokens[hash]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *token
+	return &found, nil
+}
+
+func (f *fakeTokenRepository) RotateRefreshToken(old *models.RefreshToken, next *models.RefreshToken, ctx context.Context) error {
+	stored := f.tokens[old.TokenHash]
+	if stored.RevokedAt != nil {
+		return repositories.ErrRefreshTokenRevoked
+	}
+	now := time.Now()
+	stored.RevokedAt = &now
+	old.RevokedAt = &now
+	return f.CreateRefreshToken(next, ctx)
+}
+
+func (f *fakeTokenRepository) RevokeRefreshTokenFamily(familyID string, ctx context.Context) error {
+	now := time.Now()
+	for _, token := range f.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (f *fakeTokenRepository) RevokeAllUserTokens(userID uint, ctx context.Context) error {
+	now := time.Now()
+	for _, token := range f.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	f.users.users[userID].TokenVersion++
+	return nil
+}
+
+func (f *fakeTokenRepository) IsAccessTokenRevoked(jti string, ctx context.Context) (bool, error) {
+	return f.revoked[jti], nil
+}
+
+// fakeUserRepository keeps users in memory, keyed by their ID.
+type fakeUserRepository struct {
+	repositories.UserRepository
+	users map[uint]*models.User
+}
+
+func (f *fakeUserRepository) FindByID(id uint, ctx context.Context) (*models.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *user
+	return &found, nil
+}
+
+func newTestTokenService(t *testing.T) (*TokenService, *fakeTokenRepository, *models.User) {
+	t.Helper()
+	user := &models.User{ID: 1, Email: "user@example.com"}
+	users := &fakeUserRepository{users: map[uint]*models.User{user.ID: user}}
+	tokens := newFakeTokenRepository(users)
+	keys := jwtkeys.NewHMAC([]byte("test secret of sufficient length"))
+	return NewTokenService(tokens, users, keys, 15*time.Minute, time.Hour), tokens, user
+}
+
+func assertInvalidToken(t *testing.T, err error) {
+	t.Helper()
+	var appErr *apperr.Error
+	if !errors.As(err, &appErr) || appErr.Code != apperr.CodeInvalidToken {
+		t.Fatalf("got error %v, want an invalid token error", err)
+	}
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	ctx := context.Background()
+	ts, tokens, user := newTestTokenService(t)
+
+	issued, err := ts.IssueTokens(user, ctx)
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+	refreshed, err := ts.Refresh(issued.RefreshToken, ctx)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	if refreshed.RefreshToken == issued.RefreshToken {
+		t.Fatal("refresh token was not rotated")
+	}
+	old := tokens.tokens[hashToken(issued.RefreshToken)]
+	next := tokens.tokens[hashToken(refreshed.RefreshToken)]
+	if old.RevokedAt == nil {
+		t.Error("rotated refresh token is not revoked")
+	}
+	if next.RevokedAt != nil {
+		t.Error("new refresh token is revoked")
+	}
+	if next.FamilyID != old.FamilyID {
+		t.Errorf("new refresh token is in family %q, want %q", next.FamilyID, old.FamilyID)
+	}
+	if _, err := ts.ParseAccessToken(refreshed.Token); err != nil {
+		t.Errorf("ParseAccessToken: %v", err)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	ctx := context.Background()
+	ts, tokens, user := newTestTokenService(t)
+
+	issued, err := ts.IssueTokens(user, ctx)
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+	other, err := ts.IssueTokens(user, ctx)
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+	refreshed, err := ts.Refresh(issued.RefreshToken, ctx)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	_, err = ts.Refresh(issued.RefreshToken, ctx)
+	assertInvalidToken(t, err)
+
+	// The token handed out by the legitimate rotation is revoked along with the family.
+	_, err = ts.Refresh(refreshed.RefreshToken, ctx)
+	assertInvalidToken(t, err)
+
+	// Tokens of other logins are left alone.
+	if tokens.tokens[hashToken(other.RefreshToken)].RevokedAt != nil {
+		t.Error("refresh token of another family was revoked")
+	}
+	if _, err := ts.Refresh(other.RefreshToken, ctx); err != nil {
+		t.Errorf("Refresh of another family: %v", err)
+	}
+}
+
+func TestTokenVersionRevokesAccessTokens(t *testing.T) {
+	ctx := context.Background()
+	ts, _, user := newTestTokenService(t)
+
+	issued, err := ts.IssueTokens(user, ctx)
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+	claims, err := ts.ParseAccessToken(issued.Token)
+	if err != nil {
+		t.Fatalf("ParseAccessToken: %v", err)
+	}
+	if revoked, err := ts.IsRevoked(claims, user, ctx); err != nil || revoked {
+		t.Fatalf("IsRevoked = %v, %v before LogoutAll, want false", revoked, err)
+	}
+
+	if err := ts.LogoutAll(user, ctx); err != nil {
+		t.Fatalf("LogoutAll: %v", err)
+	}
+	if revoked, err := ts.IsRevoked(claims, user, ctx); err != nil || !revoked {
+		t.Errorf("IsRevoked = %v, %v after LogoutAll, want true", revoked, err)
+	}
+
+	// Tokens issued with the new version are accepted again.
+	reissued, err := ts.IssueTokens(user, ctx)
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+	claims, err = ts.ParseAccessToken(reissued.Token)
+	if err != nil {
+		t.Fatalf("ParseAccessToken: %v", err)
+	}
+	if revoked, err := ts.IsRevoked(claims, user, ctx); err != nil || revoked {
+		t.Errorf("IsRevoked = %v, %v for a new token, want false", revoked, err)
+	}
+}
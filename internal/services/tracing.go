@@ -0,0 +1,6 @@
+package services
+
+import "go.opentelemetry.io/otel"
+
+// tracer starts the spans of service methods, as children of the request span.
+var tracer = otel.Tracer("shop/internal/services")
@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"shop/internal/apperr"
+	"shop/internal/dto"
+	"shop/internal/models"
+	"shop/internal/repositories"
+	"shop/internal/totp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// recoveryCodeCount is how many recovery codes a user gets at a time.
+	recoveryCodeCount = 10
+	// maxChallengeAttempts is how many codes can be tried on a login challenge before it
+	// has to be started over with the password.
+	maxChallengeAttempts = 5
+	// totpSkew is how many time steps of clock drift between the server and the
+	// authenticator app are tolerated in either direction.
+	totpSkew = 1
+)
+
+// TwoFactorService manages TOTP two-factor authentication. Users with it enabled log in
+// in two steps: the password gets them a short-lived login challenge, which is
+// exchanged together with a code from the authenticator app or a recovery code for
+// the token pair.
+type TwoFactorService struct {
+	twoFactorRepository repositories.TwoFactorRepository
+	userRepository      repositories.UserRepository
+	tokenService        *TokenService
+	lockoutService      *LockoutService
+	issuer              string
+	challengeTTL        time.Duration
+}
+
+// Enroll generates a new authenticator app secret for the user. Two-factor
+// authentication is enabled once the secret is confirmed with a code.
+func (ts *TwoFactorService) Enroll(user *models.User, ctx context.Context) (*dto.TOTPEnrollmentResponse, error) {
+	enabled, err := ts.IsEnabled(user.ID, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, apperr.Conflict("two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	err = ts.twoFactorRepository.SaveCredential(&models.TOTPCredential{UserID: user.ID, Secret: secret}, ctx)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	return &dto.TOTPEnrollmentResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(secret, ts.issuer, user.Email),
+	}, nil
+}
+
+// Confirm enables two-factor authentication once the user proves the authenticator app
+// has the secret. It returns the recovery codes of the user.
+func (ts *TwoFactorService) Confirm(user *models.User, req dto.TwoFactorCodeRequest, ctx context.Context) (*dto.RecoveryCodesResponse, error) {
+	credential, err := ts.twoFactorRepository.FindCredential(user.ID, ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperr.BadRequest("two-factor enrollment has not been started")
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if credential.ConfirmedAt != nil {
+		return nil, apperr.Conflict("two-factor authentication is already enabled")
+	}
+
+	step, ok := totp.Validate(credential.Secret, req.Code, time.Now(), totpSkew)
+	if !ok {
+		return nil, invalidTwoFactorCode()
+	}
+
+	codes, records, err := newRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if err := ts.twoFactorRepository.Confirm(credential, step, records, ctx); err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// Disable turns two-factor authentication off. It takes a code like the login does.
+func (ts *TwoFactorService) Disable(user *models.User, req dto.TwoFactorCodeRequest, clientIP string, ctx context.Context) error {
+	if err := ts.requireCode(user, req.Code, clientIP, ctx); err != nil {
+		return err
+	}
+
+	if err := ts.twoFactorRepository.Disable(user.ID, ctx); err != nil {
+		return apperr.Internal(err)
+	}
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user, used or not.
+func (ts *TwoFactorService) RegenerateRecoveryCodes(user *models.User, req dto.TwoFactorCodeRequest, clientIP string, ctx context.Context) (*dto.RecoveryCodesResponse, error) {
+	if err := ts.requireCode(user, req.Code, clientIP, ctx); err != nil {
+		return nil, err
+	}
+
+	codes, records, err := newRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if err := ts.twoFactorRepository.ReplaceRecoveryCodes(user.ID, records, ctx); err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// IsEnabled reports whether the user has confirmed an authenticator app.
+func (ts *TwoFactorService) IsEnabled(userID uint, ctx context.Context) (bool, error) {
+	credential, err := ts.twoFactorRepository.FindCredential(userID, ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, apperr.Internal(err)
+	}
+
+	return credential.ConfirmedAt != nil, nil
+}
+
+// StartLogin issues a login challenge to a user who has entered the right password.
+func (ts *TwoFactorService) StartLogin(user *models.User, ctx context.Context) (*dto.LoginResponse, error) {
+	token, err := randomToken()
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	err = ts.twoFactorRepository.CreateChallenge(&models.LoginChallenge{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ts.challengeTTL),
+	}, ctx)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	return &dto.LoginResponse{
+		TwoFactorRequired:  true,
+		ChallengeToken:     token,
+		ChallengeExpiresIn: int(ts.challengeTTL.Seconds()),
+	}, nil
+}
+
+// CompleteLogin exchanges a login challenge and a code for the token pair. Wrong codes
+// count as failed logins of the user's email, so they lead to the same delays and
+// lockout as wrong passwords.
+func (ts *TwoFactorService) CompleteLogin(req dto.TwoFactorLoginRequest, clientIP string, ctx context.Context) (*dto.TokenResponse, error) {
+	ctx, span := tracer.Start(ctx, "TwoFactorService.CompleteLogin")
+	defer span.End()
+
+	invalidChallenge := apperr.Unauthorized("invalid or expired login challenge").WithCode(apperr.CodeInvalidToken)
+
+	challenge, err := ts.twoFactorRepository.FindChallengeByHash(hashToken(req.ChallengeToken), ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, invalidChallenge
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		return nil, invalidChallenge
+	}
+	// The attempt is counted before the code is checked, so concurrent requests can't
+	// try more codes than the challenge allows.
+	err = ts.twoFactorRepository.AddChallengeAttempt(challenge, maxChallengeAttempts, ctx)
+	if errors.Is(err, repositories.ErrLoginChallengeExhausted) {
+		return nil, invalidChallenge
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	user, err := ts.userRepository.FindByID(challenge.UserID, ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, invalidChallenge
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if err := ts.lockoutService.Check(user.Email, clientIP, ctx); err != nil {
+		return nil, err
+	}
+
+	credential, err := ts.twoFactorRepository.FindCredential(user.ID, ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, invalidChallenge
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	ok, err := ts.verifyCode(credential, req.Code, ctx)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if !ok {
+		if err := ts.lockoutService.RecordFailure(user.Email, clientIP, user, ctx); err != nil {
+			return nil, apperr.Internal(err)
+		}
+		return nil, invalidTwoFactorCode()
+	}
+
+	err = ts.twoFactorRepository.ConsumeChallenge(challenge, ctx)
+	if errors.Is(err, repositories.ErrLoginChallengeUsed) {
+		return nil, invalidChallenge
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if err := ts.lockoutService.RecordSuccess(user.Email, ctx); err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if user.BannedAt != nil {
+		return nil, apperr.Forbidden("account is banned").WithCode(apperr.CodeAccountBanned)
+	}
+
+	tokens, err := ts.tokenService.IssueTokens(user, ctx)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	return tokens, nil
+}
+
+// requireCode checks a code of a user who has two-factor authentication enabled. Like
+// in CompleteLogin, wrong codes count as failed logins of the user's email, so a stolen
+// access token can't be used to guess codes without running into the lockout.
+func (ts *TwoFactorService) requireCode(user *models.User, code string, clientIP string, ctx context.Context) error {
+	notEnabled := apperr.BadRequest("two-factor authentication is not enabled")
+
+	credential, err := ts.twoFactorRepository.FindCredential(user.ID, ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return notEnabled
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if credential.ConfirmedAt == nil {
+		return notEnabled
+	}
+	if err := ts.lockoutService.Check(user.Email, clientIP, ctx); err != nil {
+		return err
+	}
+
+	ok, err := ts.verifyCode(credential, code, ctx)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if !ok {
+		if err := ts.lockoutService.RecordFailure(user.Email, clientIP, user, ctx); err != nil {
+			return apperr.Internal(err)
+		}
+		return invalidTwoFactorCode()
+	}
+	if err := ts.lockoutService.RecordSuccess(user.Email, ctx); err != nil {
+		return apperr.Internal(err)
+	}
+
+	return nil
+}
+
+// verifyCode accepts a code from the authenticator app of a confirmed credential or an
+// unused recovery code. Either can be used once.
+func (ts *TwoFactorService) verifyCode(credential *models.TOTPCredential, code string, ctx context.Context) (bool, error) {
+	if credential.ConfirmedAt == nil {
+		return false, nil
+	}
+
+	code = strings.ReplaceAll(code, " ", "")
+	if step, ok := totp.Validate(credential.Secret, code, time.Now(), totpSkew); ok {
+		err := ts.twoFactorRepository.UseStep(credential.UserID, step, ctx)
+		if errors.Is(err, repositories.ErrTOTPStepUsed) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	err := ts.twoFactorRepository.UseRecoveryCode(credential.UserID, hashToken(normalizeRecoveryCode(code)), ctx)
+	if errors.Is(err, repositories.ErrRecoveryCodeNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func invalidTwoFactorCode() *apperr.Error {
+	return apperr.Unauthorized("invalid two-factor code").WithCode(apperr.CodeInvalidTwoFactor)
+}
+
+// newRecoveryCodes generates a set of recovery codes formatted as xxxxx-xxxxx, along
+// with the records storing their hashes.
+func newRecoveryCodes(userID uint) ([]string, []models.RecoveryCode, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))[:10]
+
+		codes = append(codes, code[:5]+"-"+code[5:])
+		records = append(records, models.RecoveryCode{UserID: userID, CodeHash: hashToken(code)})
+	}
+
+	return codes, records, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
+
+func NewTwoFactorService(
+	twoFactorRepo repositories.TwoFactorRepository,
+	userRepo repositories.UserRepository,
+	tokenService *TokenService,
+	lockoutService *LockoutService,
+	issuer string,
+	challengeTTL time.Duration) *TwoFactorService {
+
+	return &TwoFactorService{
+		twoFactorRepository: twoFactorRepo,
+		userRepository:      userRepo,
+		tokenService:        tokenService,
+		lockoutService:      lockoutService,
+		issuer:              issuer,
+		challengeTTL:        challengeTTL,
+	}
+}
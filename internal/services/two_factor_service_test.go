@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"shop/internal/apperr"
+	"shop/internal/mailer"
+	"shop/internal/models"
+	"shop/internal/repositories"
+	"shop/internal/totp"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakeTwoFactorRepository holds the TOTP credential of a single user.
+type fakeTwoFactorRepository struct {
+	repositories.TwoFactorRepository
+	credential *models.TOTPCredential
+}
+
+func (f *fakeTwoFactorRepository) FindCredential(userID uint, ctx context.Context) (*models.TOTPCredential, error) {
+	if f.credential == nil || f.credential.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *f.credential
+	return &found, nil
+}
+
+func (f *fakeTwoFactorRepository) UseStep(userID uint, step int64, ctx context.Context) error {
+	if f.credential.LastUsedStep >= step {
+		return repositories.ErrTOTPStepUsed
+	}
+	f.credential.LastUsedStep = step
+	return nil
+}
+
+func (f *fakeTwoFactorRepository) UseRecoveryCode(userID uint, hash string, ctx context.Context) error {
+	return repositories.ErrRecoveryCodeNotFound
+}
+
+// fakeLoginAttemptRepository keeps failed logins and lockouts in memory. It is used
+// from the goroutine sending unlock links as well, hence the mutex.
+type fakeLoginAttemptRepository struct {
+	mu       sync.Mutex
+	failures []models.LoginFailure
+	lockouts map[string]models.LoginLockout
+}
+
+func (f *fakeLoginAttemptRepository) RecordFailure(failure *models.LoginFailure, expiredBefore time.Time, ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	failure.CreatedAt = time.Now()
+	f.failures = append(f.failures, *failure)
+	return nil
+}
+
+func (f *fakeLoginAttemptRepository) FailuresByEmail(email string, since time.Time, ctx context.Context) (*repositories.LoginFailureStats, error) {
+	return f.stats(func(failure models.LoginFailure) bool { return failure.Email == email }, since), nil
+}
+
+func (f *fakeLoginAttemptRepository) FailuresByIP(ip string, since time.Time, ctx context.Context) (*repositories.LoginFailureStats, error) {
+	return f.stats(func(failure models.LoginFailure) bool { return failure.IP == ip }, since), nil
+}
+
+func (f *fakeLoginAttemptRepository) stats(match func(models.LoginFailure) bool, since time.Time) *repositories.LoginFailureStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var stats repositories.LoginFailureStats
+	for _, failure := range f.failures {
+		if !match(failure) || failure.CreatedAt.Before(since) {
+			continue
+		}
+		stats.Count++
+		if stats.FirstAt == nil {
+			stats.FirstAt = &failure.CreatedAt
+		}
+		stats.LastAt = &failure.CreatedAt
+	}
+	return &stats
+}
+
+func (f *fakeLoginAttemptRepository) ClearFailures(email string, ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var kept []models.LoginFailure
+	for _, failure := range f.failures {
+		if failure.Email != email {
+			kept = append(kept, failure)
+		}
+	}
+	f.failures = kept
+	return nil
+}
+
+func (f *fakeLoginAttemptRepository) FindLockout(email string, ctx context.Context) (*models.LoginLockout, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lockout, ok := f.lockouts[email]
+	if !ok || !lockout.LockedUntil.After(time.Now()) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &lockout, nil
+}
+
+func (f *fakeLoginAttemptRepository) Lock(lockout *models.LoginLockout, ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lockouts[lockout.Email] = *lockout
+	return nil
+}
+
+func (f *fakeLoginAttemptRepository) Unlock(email string, ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.lockouts, email)
+	return nil
+}
+
+// fakeAccountUnlockRepository accepts unlock tokens without keeping them.
+type fakeAccountUnlockRepository struct {
+	repositories.AccountUnlockRepository
+}
+
+func (f *fakeAccountUnlockRepository) Create(token *models.AccountUnlockToken, ctx context.Context) error {
+	return nil
+}
+
+const testMaxFailures = 3
+
+func newTestTwoFactorService(t *testing.T) (*TwoFactorService, *fakeLoginAttemptRepository, *models.User) {
+	t.Helper()
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	confirmedAt := time.Now()
+	user := &models.User{ID: 1, Email: "user@example.com"}
+	twoFactor := &fakeTwoFactorRepository{
+		credential: &models.TOTPCredential{UserID: user.ID, Secret: secret, ConfirmedAt: &confirmedAt},
+	}
+	attempts := &fakeLoginAttemptRepository{lockouts: map[string]models.LoginLockout{}}
+	lockout := NewLockoutService(attempts, &fakeAccountUnlockRepository{}, mailer.NewMemoryMailer(), "http://localhost", time.Hour,
+		LockoutPolicy{MaxFailures: testMaxFailures, Window: 15 * time.Minute, LockDuration: 15 * time.Minute, MaxIPFailures: 100},
+		slog.New(slog.DiscardHandler))
+
+	return NewTwoFactorService(twoFactor, nil, nil, lockout, "Shop", 5*time.Minute), attempts, user
+}
+
+func currentCode(t *testing.T, ts *TwoFactorService) string {
+	t.Helper()
+	twoFactor := ts.twoFactorRepository.(*fakeTwoFactorRepository)
+	code, err := totp.Code(twoFactor.credential.Secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatalf("Code: %v", err)
+	}
+	return code
+}
+
+func assertErrorCode(t *testing.T, err error, want apperr.Code) {
+	t.Helper()
+	var appErr *apperr.Error
+	if !errors.As(err, &appErr) || appErr.Code != want {
+		t.Fatalf("got error %v, want code %s", err, want)
+	}
+}
+
+func wrongCode(code string) string {
+	if code == "000000" {
+		return "111111"
+	}
+	return "000000"
+}
+
+func TestRequireCodeLocksOutAfterFailures(t *testing.T) {
+	ctx := context.Background()
+	ts, attempts, user := newTestTwoFactorService(t)
+	code := currentCode(t, ts)
+
+	for i := 1; i <= testMaxFailures; i++ {
+		err := ts.requireCode(user, wrongCode(code), "192.0.2.1", ctx)
+		assertErrorCode(t, err, apperr.CodeInvalidTwoFactor)
+
+		stats, _ := attempts.FailuresByEmail(user.Email, time.Time{}, ctx)
+		if stats.Count != int64(i) {
+			t.Fatalf("%d failures recorded after %d wrong codes", stats.Count, i)
+		}
+	}
+
+	// Once locked out, even the right code is refused.
+	err := ts.requireCode(user, code, "192.0.2.1", ctx)
+	assertErrorCode(t, err, apperr.CodeAccountLocked)
+}
+
+func TestRequireCodeClearsFailures(t *testing.T) {
+	ctx := context.Background()
+	ts, attempts, user := newTestTwoFactorService(t)
+	code := currentCode(t, ts)
+
+	err := ts.requireCode(user, wrongCode(code), "192.0.2.1", ctx)
+	assertErrorCode(t, err, apperr.CodeInvalidTwoFactor)
+	if err := ts.requireCode(user, code, "192.0.2.1", ctx); err != nil {
+		t.Fatalf("requireCode with the right code: %v", err)
+	}
+
+	stats, _ := attempts.FailuresByEmail(user.Email, time.Time{}, ctx)
+	if stats.Count != 0 {
+		t.Errorf("%d failures left after the right code", stats.Count)
+	}
+}
+
+func TestRequireCodeRejectsReplay(t *testing.T) {
+	ctx := context.Background()
+	ts, _, user := newTestTwoFactorService(t)
+	code := currentCode(t, ts)
+
+	if err := ts.requireCode(user, code, "192.0.2.1", ctx); err != nil {
+		t.Fatalf("requireCode: %v", err)
+	}
+	err := ts.requireCode(user, code, "192.0.2.1", ctx)
+	assertErrorCode(t, err, apperr.CodeInvalidTwoFactor)
+}
@@ -2,112 +2,227 @@ package services
 
 import (
 	"context"
-	"net/http"
+	"errors"
+	"log/slog"
+	"shop/internal/apperr"
 	"shop/internal/dto"
 	"shop/internal/models"
 	"shop/internal/repositories"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type UserService struct {
 	userRepository repositories.UserRepository
 	cartRepository repositories.CartRepository
-	jwtSecret      string
+	tokenService   *TokenService
+
+	emailVerificationService *EmailVerificationService
+	lockoutService           *LockoutService
+	twoFactorService         *TwoFactorService
+	logger                   *slog.Logger
 }
 
-func (us *UserService) Register(req dto.RegisterRequest) (resp *dto.RegisterResponse, errMsg string, status int) {
+// Register creates an unverified customer account and emails a verification link to it.
+// Seller accounts are obtained through a seller application and administrator accounts
+// are created by other administrators.
+func (us *UserService) Register(req dto.RegisterRequest, ctx context.Context) (*dto.RegisterResponse, error) {
+	ctx, span := tracer.Start(ctx, "UserService.Register")
+	defer span.End()
+
+	user, err := us.createUser(dto.CreateUserRequest{
+		Email:    req.Email,
+		Username: req.Username,
+		Password: req.Password,
+		Type:     dto.TypeCustomer.String(),
+	}, false, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The email is sent after the response, so it must not be cancelled with the request.
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		if err := us.emailVerificationService.SendVerification(user, ctx); err != nil {
+			us.logger.ErrorContext(ctx, "Failed to send verification email", slog.Uint64("user_id", uint64(user.ID)), slog.Any("error", err))
+		}
+	}()
+
+	tokens, err := us.tokenService.IssueTokens(user, ctx)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	return &dto.RegisterResponse{Username: user.Username, Email: user.Email, TokenResponse: *tokens}, nil
+}
+
+// CreateUser creates a user of any type. Customers get a cart along with the account.
+// Emails of users created this way are trusted and marked as verified.
+func (us *UserService) CreateUser(req dto.CreateUserRequest, ctx context.Context) (*models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.CreateUser")
+	defer span.End()
+
+	return us.createUser(req, true, ctx)
+}
+
+func (us *UserService) createUser(req dto.CreateUserRequest, verified bool, ctx context.Context) (*models.User, error) {
+	userType := dto.UserType(req.Type)
+	if !userType.IsValid() {
+		return nil, apperr.BadRequest("invalid user type")
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return nil, "Something went wrong", http.StatusInternalServerError
+		return nil, apperr.Internal(err)
 	}
-	req.Password = string(hashedPassword)
 
 	user := models.User{
 		Username: req.Username,
-		Password: req.Password,
+		Password: string(hashedPassword),
 		Email:    req.Email,
-		Type:     req.Type,
+		Type:     userType.String(),
+	}
+	if verified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	err = us.userRepository.Insert(&user, ctx)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, apperr.Conflict("email is already registered").WithCode(apperr.CodeEmailTaken)
 	}
-	userCtx, userCancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer userCancel()
-	err = us.userRepository.Insert(&user, userCtx)
 	if err != nil {
-		return nil, "Something went wrong", http.StatusInternalServerError
+		return nil, apperr.Internal(err)
 	}
 
-	if user.Type == string(dto.TypeCustomer) {
+	if userType == dto.TypeCustomer {
 		cart := models.Cart{
 			UserID: user.ID,
 			User:   &user,
 		}
-		cartCtx, cartCancel := context.WithTimeout(context.Background(), 3*time.Second)
-		defer cartCancel()
-		if err := us.cartRepository.Create(&cart, cartCtx); err != nil {
-			return nil, "Something went wrong", http.StatusInternalServerError
+		if err := us.cartRepository.Create(&cart, ctx); err != nil {
+			return nil, apperr.Internal(err)
 		}
+		user.Cart = &cart
 	}
 
-	token, err := us.getJwtToken(&user)
-	if err != nil {
-		return nil, "Something went wrong", http.StatusInternalServerError
-	}
-	return &dto.RegisterResponse{Username: user.Username, Email: user.Email, Token: token}, "", http.StatusCreated
+	return &user, nil
 }
 
-func (us *UserService) Login(req dto.LoginRequest) (resp *dto.LoginResponse, errMsg string, status int) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+// Login exchanges the email and password of a user for a token pair, or for a login
+// challenge if the user has two-factor authentication enabled. Failed attempts are
+// tracked per email and client IP, and attempts with unknown emails take as long and
+// fail the same way as ones with a wrong password.
+func (us *UserService) Login(req dto.LoginRequest, clientIP string, ctx context.Context) (*dto.LoginResponse, error) {
+	ctx, span := tracer.Start(ctx, "UserService.Login")
+	defer span.End()
+
+	invalidCredentials := apperr.Unauthorized("invalid email or password").WithCode(apperr.CodeInvalidCredentials)
+
+	if err := us.lockoutService.Check(req.Email, clientIP, ctx); err != nil {
+		return nil, err
+	}
+
 	user, err := us.userRepository.FindByEmail(req.Email, ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(req.Password))
+		if err := us.lockoutService.RecordFailure(req.Email, clientIP, nil, ctx); err != nil {
+			return nil, apperr.Internal(err)
+		}
+		return nil, invalidCredentials
+	}
 	if err != nil {
-		return nil, "Invalid username or password", http.StatusUnauthorized
+		return nil, apperr.Internal(err)
 	}
 	if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return nil, "Invalid username or password", http.StatusUnauthorized
+		if err := us.lockoutService.RecordFailure(req.Email, clientIP, user, ctx); err != nil {
+			return nil, apperr.Internal(err)
+		}
+		return nil, invalidCredentials
+	}
+	if user.BannedAt != nil {
+		return nil, apperr.Forbidden("account is banned").WithCode(apperr.CodeAccountBanned)
 	}
 
-	token, err := us.getJwtToken(user)
+	// Failures are only forgotten after the second step, otherwise knowing the password
+	// would allow guessing codes without ever being locked out.
+	twoFactor, err := us.twoFactorService.IsEnabled(user.ID, ctx)
 	if err != nil {
-		return nil, "Something went wrong", http.StatusInternalServerError
+		return nil, err
+	}
+	if twoFactor {
+		return us.twoFactorService.StartLogin(user, ctx)
+	}
+	if err := us.lockoutService.RecordSuccess(req.Email, ctx); err != nil {
+		return nil, apperr.Internal(err)
 	}
 
-	return &dto.LoginResponse{Token: token}, "", http.StatusOK
+	tokens, err := us.tokenService.IssueTokens(user, ctx)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	return &dto.LoginResponse{TokenResponse: tokens}, nil
 }
 
-func (us *UserService) GetUserFromContext(c *gin.Context) (*models.User, int) {
+func (us *UserService) GetUserFromContext(c *gin.Context) (*models.User, error) {
 	cUser, exist := c.Get("user")
 	if !exist {
-		return nil, http.StatusUnauthorized
+		return nil, apperr.Unauthorized("unauthorized access")
 	}
 	user, ok := cUser.(*models.User)
 	if !ok {
-		return nil, http.StatusUnauthorized
+		return nil, apperr.Unauthorized("unauthorized access")
 	}
 
-	return user, http.StatusOK
+	return user, nil
 }
 
-func (us *UserService) getJwtToken(user *models.User) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userId": user.ID,
-		"exp":    time.Now().Add(time.Hour * 72).Unix(),
-	})
-
-	tokenString, err := token.SignedString([]byte(us.jwtSecret))
-	if err != nil {
-		return "", err
+// GetTokenClaimsFromContext returns the claims of the access token the request was
+// authenticated with.
+func (us *UserService) GetTokenClaimsFromContext(c *gin.Context) (*AccessClaims, error) {
+	cClaims, exist := c.Get("tokenClaims")
+	if !exist {
+		return nil, apperr.Unauthorized("unauthorized access")
+	}
+	claims, ok := cClaims.(*AccessClaims)
+	if !ok {
+		return nil, apperr.Unauthorized("unauthorized access")
 	}
 
-	return tokenString, nil
+	return claims, nil
 }
 
-func NewUserService(userRepo repositories.UserRepository, cartRepo repositories.CartRepository, jwt string) *UserService {
+// dummyPasswordHash is compared against when there is no user with the email, so the
+// login takes as long as with a wrong password.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+func NewUserService(
+	userRepo repositories.UserRepository,
+	cartRepo repositories.CartRepository,
+	tokenService *TokenService,
+	emailVerificationService *EmailVerificationService,
+	lockoutService *LockoutService,
+	twoFactorService *TwoFactorService,
+	logger *slog.Logger) *UserService {
+
 	return &UserService{
 		userRepository: userRepo,
 		cartRepository: cartRepo,
-		jwtSecret:      jwt,
+		tokenService:   tokenService,
+
+		emailVerificationService: emailVerificationService,
+		lockoutService:           lockoutService,
+		twoFactorService:         twoFactorService,
+		logger:                   logger,
 	}
 }
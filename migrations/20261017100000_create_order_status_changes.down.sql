@@ -0,0 +1 @@
+DROP TABLE IF EXISTS order_status_changes;
\ No newline at end of file
@@ -0,0 +1,14 @@
+CREATE TABLE order_status_changes (
+    id          BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    order_id    BIGINT UNSIGNED NOT NULL,
+    from_status VARCHAR(100),
+    to_status   VARCHAR(100) NOT NULL,
+    actor_id    BIGINT UNSIGNED,
+    created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_order_status_changes_order_id (order_id),
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+INSERT INTO order_status_changes (order_id, to_status, actor_id, created_at)
+SELECT id, status, user_id, created_at FROM orders;
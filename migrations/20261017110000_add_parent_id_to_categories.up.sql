@@ -0,0 +1,5 @@
+ALTER TABLE  categories
+ADD   COLUMN parent_id BIGINT UNSIGNED NULL,
+ADD   INDEX  idx_categories_parent_id (parent_id),
+ADD   CONSTRAINT fk_categories_parent
+      FOREIGN KEY (parent_id) REFERENCES categories(id);
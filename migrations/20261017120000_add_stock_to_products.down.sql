@@ -0,0 +1,3 @@
+ALTER TABLE products
+ DROP CHECK  chk_products_stock,
+ DROP COLUMN stock;
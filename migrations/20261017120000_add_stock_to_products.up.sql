@@ -0,0 +1,8 @@
+ALTER TABLE  products
+ADD   COLUMN stock INT NOT NULL DEFAULT 0,
+ADD   CONSTRAINT chk_products_stock CHECK (stock >= 0);
+
+-- Products listed before stock was tracked could be ordered without limit. They keep
+-- being sold that way until their sellers set the real quantities, instead of all of
+-- them going out of stock at once. New products get their stock when created.
+UPDATE products SET stock = 1000000;
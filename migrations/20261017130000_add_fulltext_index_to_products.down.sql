@@ -0,0 +1,2 @@
+ALTER TABLE products
+ DROP INDEX ft_products_name_description;
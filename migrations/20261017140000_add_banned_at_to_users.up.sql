@@ -0,0 +1,2 @@
+ALTER TABLE  users
+ADD   COLUMN banned_at TIMESTAMP NULL;
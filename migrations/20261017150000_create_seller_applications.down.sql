@@ -0,0 +1 @@
+DROP TABLE IF EXISTS seller_applications;
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE seller_applications (
+    id          BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id     BIGINT UNSIGNED NOT NULL,
+    message     VARCHAR(500),
+    status      VARCHAR(100) NOT NULL,
+    reviewer_id BIGINT UNSIGNED,
+    created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    reviewed_at TIMESTAMP NULL,
+    INDEX idx_seller_applications_user_id (user_id),
+    INDEX idx_seller_applications_status (status),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (reviewer_id) REFERENCES users(id) ON DELETE SET NULL
+);
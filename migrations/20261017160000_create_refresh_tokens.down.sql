@@ -0,0 +1,5 @@
+ALTER TABLE users
+ DROP COLUMN token_version;
+
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
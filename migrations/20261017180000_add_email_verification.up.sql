@@ -0,0 +1,16 @@
+ALTER TABLE  users
+ADD   COLUMN email_verified_at TIMESTAMP NULL;
+
+-- Accounts created before verification was introduced are trusted as they are.
+UPDATE users
+   SET email_verified_at = CURRENT_TIMESTAMP;
+
+CREATE TABLE email_verification_tokens (
+    id         BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id    BIGINT UNSIGNED NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_email_verification_tokens_user_id (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,51 @@
+// Package migrations holds the SQL migrations of the database schema. They are
+// embedded into the binary and applied with the migrate command.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// Version is the version of the latest migration, i.e. the schema version the
+// application expects.
+var Version = latestVersion()
+
+// New returns a migrator of the database with the given go-sql-driver/mysql DSN.
+func New(dsn string) (*migrate.Migrate, error) {
+	source, err := iofs.New(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.NewWithSourceInstance("iofs", source, "mysql://"+dsn)
+}
+
+func latestVersion() uint {
+	names, err := fs.Glob(files, "*.up.sql")
+	if err != nil {
+		panic(err)
+	}
+
+	var latest uint
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			panic(fmt.Sprintf("migration %s has no version prefix", name))
+		}
+		latest = max(latest, uint(version))
+	}
+
+	return latest
+}